@@ -0,0 +1,169 @@
+// Command gorpg-headless plays a game without a window. It reads one input
+// per line from a script file (or stdin), e.g.
+//
+//	# walk to the rat and fight it
+//	right 12
+//	down
+//
+// and prints the resulting level. Blank lines and lines starting with # are
+// ignored, and an optional count repeats the input. travel, attack and
+// inspect take a position instead, e.g. "travel 4 3"; explore and travel
+// keep walking until they stop of their own accord. The random seed defaults
+// to 1 so that the same script always gives the same result. It must be run
+// from the repository root so the map files can be found.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/michaelilao/gorpg/game"
+)
+
+func main() {
+	trace := flag.Bool("trace", false, "print the level after every script line")
+	seed := flag.Int64("seed", 1, "random seed")
+	fovName := flag.String("fov", "shadowcast", "field of view: ray, shadowcast or permissive")
+	flag.Parse()
+
+	fov, err := game.ParseFOV(*fovName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	var in io.Reader = os.Stdin
+	if flag.NArg() > 0 {
+		file, err := os.Open(flag.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer file.Close()
+		in = file
+	}
+
+	g, err := game.NewGame(0, *seed)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	g.SetFOV(fov)
+	level := g.CurrentLevel
+
+	scanner := bufio.NewScanner(in)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		typ, err := game.ParseInputType(fields[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "line %d: %v\n", lineNum, err)
+			os.Exit(1)
+		}
+		input := game.Input{Typ: typ}
+		count := 1
+		if typ.NeedsTarget() {
+			input.Target, err = parsePos(fields[1:])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "line %d: %v\n", lineNum, err)
+				os.Exit(1)
+			}
+		} else if len(fields) > 1 {
+			count, err = strconv.Atoi(fields[1])
+			if err != nil || count < 1 {
+				fmt.Fprintf(os.Stderr, "line %d: bad count %q\n", lineNum, fields[1])
+				os.Exit(1)
+			}
+		}
+		if typ == game.QuitGame {
+			break
+		}
+		for i := 0; i < count; i++ {
+			level = g.Step(&input)
+			for g.Traveling() {
+				level = g.StepTravel()
+			}
+		}
+		if *trace {
+			fmt.Printf("> %s\n", line)
+			printLevel(os.Stdout, level)
+		}
+	}
+	err = scanner.Err()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if !*trace {
+		printLevel(os.Stdout, level)
+	}
+}
+
+// parsePos reads the "x y" after a travel, attack or inspect input.
+func parsePos(fields []string) (game.Pos, error) {
+	if len(fields) != 2 {
+		return game.Pos{}, fmt.Errorf("want an x and y")
+	}
+	x, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return game.Pos{}, fmt.Errorf("bad x %q", fields[0])
+	}
+	y, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return game.Pos{}, fmt.Errorf("bad y %q", fields[1])
+	}
+	return game.Pos{X: x, Y: y}, nil
+}
+
+func printLevel(w io.Writer, level *game.Level) {
+	p := level.Player
+	fmt.Fprintf(w, "level %s\n", level.Name)
+	fmt.Fprint(w, level)
+	fmt.Fprintf(w, "player %d,%d hp %d turns %d kills %d\n", p.X, p.Y, p.Hitpoints, p.Turns, p.Kills)
+	if p.Dead() {
+		fmt.Fprintf(w, "dead killed by %s\n", p.KilledBy)
+	}
+	for _, effect := range p.Effects {
+		fmt.Fprintf(w, "effect %s %d\n", effect.Name, effect.Ticks)
+	}
+	for _, item := range p.Inventory {
+		if item.Equipped {
+			fmt.Fprintf(w, "inventory %s (equipped)\n", item.Name)
+		} else {
+			fmt.Fprintf(w, "inventory %s\n", item.Name)
+		}
+	}
+	monsters := make([]*game.Monster, 0, len(level.Monsters))
+	for _, monster := range level.Monsters {
+		monsters = append(monsters, monster)
+	}
+	sort.Slice(monsters, func(i, j int) bool {
+		a, b := monsters[i].Pos, monsters[j].Pos
+		return a.Y < b.Y || a.Y == b.Y && a.X < b.X
+	})
+	for _, m := range monsters {
+		fmt.Fprintf(w, "monster %s %d,%d hp %d\n", m.Name, m.X, m.Y, m.Hitpoints)
+	}
+	i := level.EventPos
+	for {
+		if level.Events[i] != "" {
+			fmt.Fprintf(w, "event %s\n", level.Events[i])
+		}
+		i = (i + 1) % len(level.Events)
+		if i == level.EventPos {
+			break
+		}
+	}
+}
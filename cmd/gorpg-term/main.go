@@ -0,0 +1,39 @@
+// Command gorpg-term plays the game in a terminal instead of an SDL window.
+// Move with the arrow keys or h/j/k/l (y/u/b/n for diagonals), save with
+// F5, load with F9 and quit with q.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/michaelilao/gorpg/game"
+	"github.com/michaelilao/gorpg/tui"
+)
+
+func main() {
+	seed := flag.Int64("seed", time.Now().UnixNano(), "random seed, to replay a previous game")
+	fovName := flag.String("fov", "shadowcast", "field of view: ray, shadowcast or permissive")
+	flag.Parse()
+
+	fov, err := game.ParseFOV(*fovName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	game, err := game.NewGame(1, *seed)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	game.SetFOV(fov)
+
+	go func() {
+		ui := tui.NewUI(game.InputChan, game.LevelChans[0])
+		ui.Run()
+	}()
+	game.Run()
+	fmt.Println("Done, seed", game.Seed)
+}
@@ -0,0 +1,153 @@
+package game
+
+// AIState is what a monster is currently doing.
+type AIState int
+
+const (
+	Resting AIState = iota
+	Wandering
+	Hunting
+	Fleeing
+)
+
+// calmState is the state a monster returns to when it isn't aware of the
+// player.
+func (m *Monster) calmState() AIState {
+	if m.Behaviour == Wander {
+		return Wandering
+	}
+	return Resting
+}
+
+// Update carries out one action for the monster and returns the energy it
+// cost. Monsters rest or wander until they see the player or hear them
+// nearby, hunt them down while they can see them or remember where they
+// were, and run away once they are badly hurt.
+func (m *Monster) Update(level *Level) float64 {
+	player := level.Player
+	m.look(level)
+	sees := m.sight[player.Pos]
+	heard := false
+	if sees {
+		m.LastKnown = player.Pos
+		m.Lost = 0
+	} else if m.hears(level) {
+		heard = true
+		m.LastKnown = level.NoisePos
+		m.Lost = 0
+	} else {
+		m.Lost++
+	}
+	m.think(level, sees, heard)
+
+	switch m.State {
+	case Wandering:
+		return m.wander(level)
+	case Hunting:
+		return m.hunt(level)
+	case Fleeing:
+		return m.flee(level)
+	}
+	return actionCost
+}
+
+// think moves the monster between states. A monster that hears the player
+// without seeing them goes to look where the noise came from.
+func (m *Monster) think(level *Level, sees, heard bool) {
+	if m.Behaviour == Stationary {
+		m.State = Resting
+		if sees {
+			m.State = Hunting
+		}
+		return
+	}
+
+	hurt := m.FleeAt > 0 && m.Hitpoints*100 < m.MaxHitpoints*m.FleeAt
+	switch {
+	case hurt && (sees || m.Lost <= m.Memory):
+		if m.State != Fleeing {
+			level.AddEvent(Combat, m.Name+" flees")
+		}
+		m.State = Fleeing
+	case sees:
+		if m.State != Hunting {
+			level.AddEvent(Discovery, m.Name+" notices "+level.Player.Name)
+		}
+		m.State = Hunting
+	case heard:
+		if m.State != Hunting {
+			level.AddEvent(Discovery, m.Name+" hears something")
+		}
+		m.State = Hunting
+	case m.State == Hunting && (m.Lost > m.Memory || m.Pos == m.LastKnown):
+		m.State = m.calmState()
+	case m.State == Fleeing:
+		m.State = m.calmState()
+	}
+}
+
+func (m *Monster) wander(level *Level) float64 {
+	var options []Pos
+	for _, next := range getNeighbors(level, m.Pos, true) {
+		if next != level.Player.Pos {
+			options = append(options, next)
+		}
+	}
+	if len(options) == 0 {
+		return actionCost
+	}
+	return m.Move(options[level.game.rng.Intn(len(options))], level)
+}
+
+// hunt closes in on the player, or on where the monster last saw or heard
+// them. Monsters that can see the player all follow the same distance map
+// rather than each searching for a path.
+func (m *Monster) hunt(level *Level) float64 {
+	playerPos := level.Player.Pos
+	if level.adjacent(m.Pos, playerPos) {
+		return m.Move(playerPos, level)
+	}
+	if m.Behaviour == Stationary {
+		return actionCost
+	}
+	if m.LastKnown == playerPos {
+		next, ok := level.towardPlayer().downhill(level, m.Pos)
+		if ok {
+			return m.Move(next, level)
+		}
+		return actionCost
+	}
+	positions := level.astar(m.Pos, m.LastKnown)
+	if len(positions) > 1 {
+		return m.Move(positions[1], level)
+	}
+	return actionCost
+}
+
+// flee heads down the level's map for running away from the player,
+// turning to fight only when cornered.
+func (m *Monster) flee(level *Level) float64 {
+	next, ok := level.awayFromPlayer().downhill(level, m.Pos)
+	if ok {
+		return m.Move(next, level)
+	}
+	if level.adjacent(m.Pos, level.Player.Pos) {
+		return m.Move(level.Player.Pos, level)
+	}
+	return actionCost
+}
+
+// look works out what the monster can see from where it stands with the
+// same field of view algorithm as the player.
+func (m *Monster) look(level *Level) {
+	m.sight = make(map[Pos]bool)
+	level.game.FOV.Compute(level, m.Pos, m.SightRange, func(pos Pos) {
+		m.sight[pos] = true
+	})
+}
+
+func distSq(a, b Pos) int {
+	dx := a.X - b.X
+	dy := a.Y - b.Y
+	return dx*dx + dy*dy
+}
@@ -0,0 +1,47 @@
+package game
+
+// Dead reports whether the player has run out of hitpoints. Frontends check
+// it on every level they receive to switch to their game over screen.
+func (p *Player) Dead() bool {
+	return p.Hitpoints <= 0
+}
+
+func (level *Level) recordKill(killer, victim *Character) {
+	p := level.Player
+	if victim == &p.Character {
+		p.KilledBy = killer.Name
+		level.AddEvent(Combat, "You have died")
+	} else if killer == &p.Character {
+		p.Kills++
+	}
+}
+
+// handleDeadInput is handleInput once the player has died: movement and
+// item inputs are ignored until the game is restarted or a save is loaded.
+func (game *Game) handleDeadInput(input *Input) float64 {
+	switch input.Typ {
+	case Restart:
+		game.restart()
+	case LoadGame:
+		game.loadFromFile()
+	case CloseWindow:
+		game.closeWindow(input.LevelChannel)
+	}
+	return 0
+}
+
+// restart throws away the current run and starts again from the map files.
+// The random number generator carries on from where it was, so a restarted
+// game can still be replayed from the original seed.
+func (game *Game) restart() {
+	levels, current := game.Levels, game.CurrentLevel
+	err := game.buildWorld()
+	if err != nil {
+		game.setLevels(levels)
+		game.CurrentLevel = current
+		current.AddEvent(System, "Could not restart: "+err.Error())
+		return
+	}
+	game.Adventure++
+	game.CurrentLevel.AddEvent(System, "A new adventure begins")
+}
@@ -0,0 +1,95 @@
+package game
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// Dice is a damage roll such as 2d6+1.
+type Dice struct {
+	Count int
+	Sides int
+	Bonus int
+}
+
+// ParseDice reads dice written as "NdS", "NdS+B" or "NdS-B".
+func ParseDice(s string) (Dice, error) {
+	var d Dice
+	s = strings.TrimSpace(strings.ToLower(s))
+	countStr, rest, found := strings.Cut(s, "d")
+	if !found {
+		return d, fmt.Errorf("bad dice %q", s)
+	}
+	sidesStr := rest
+	bonusStr := ""
+	if i := strings.IndexAny(rest, "+-"); i >= 0 {
+		sidesStr = rest[:i]
+		bonusStr = rest[i:]
+	}
+
+	var err error
+	d.Count, err = strconv.Atoi(countStr)
+	if err != nil || d.Count < 1 {
+		return d, fmt.Errorf("bad dice %q", s)
+	}
+	d.Sides, err = strconv.Atoi(sidesStr)
+	if err != nil || d.Sides < 1 {
+		return d, fmt.Errorf("bad dice %q", s)
+	}
+	if bonusStr != "" {
+		d.Bonus, err = strconv.Atoi(bonusStr)
+		if err != nil {
+			return d, fmt.Errorf("bad dice %q", s)
+		}
+	}
+	return d, nil
+}
+
+func (d Dice) String() string {
+	s := strconv.Itoa(d.Count) + "d" + strconv.Itoa(d.Sides)
+	if d.Bonus != 0 {
+		s += signed(d.Bonus)
+	}
+	return s
+}
+
+func (d Dice) roll(r *rand.Rand) int {
+	total := d.Bonus
+	for i := 0; i < d.Count; i++ {
+		total += r.Intn(d.Sides) + 1
+	}
+	return total
+}
+
+// countingSource counts how many values have been drawn from the game's
+// random source, so a saved game can rebuild the generator at exactly the
+// same point by reseeding and discarding that many values.
+type countingSource struct {
+	src   rand.Source64
+	draws uint64
+}
+
+func newCountingSource(seed int64, draws uint64) *countingSource {
+	s := &countingSource{src: rand.NewSource(seed).(rand.Source64)}
+	for s.draws < draws {
+		s.Uint64()
+	}
+	return s
+}
+
+func (s *countingSource) Int63() int64 {
+	s.draws++
+	return s.src.Int63()
+}
+
+func (s *countingSource) Uint64() uint64 {
+	s.draws++
+	return s.src.Uint64()
+}
+
+func (s *countingSource) Seed(seed int64) {
+	s.src.Seed(seed)
+	s.draws = 0
+}
@@ -0,0 +1,31 @@
+package game
+
+import "testing"
+
+func TestParseDice(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Dice
+	}{
+		{"1d6", Dice{1, 6, 0}},
+		{"2d8+3", Dice{2, 8, 3}},
+		{"3D4-1", Dice{3, 4, -1}},
+		{" 1d20 ", Dice{1, 20, 0}},
+	}
+	for _, test := range tests {
+		got, err := ParseDice(test.in)
+		if err != nil {
+			t.Errorf("ParseDice(%q): %v", test.in, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("ParseDice(%q) = %v, want %v", test.in, got, test.want)
+		}
+	}
+
+	for _, in := range []string{"", "6", "d6", "0d6", "2d0", "2d", "xd6", "2d6+", "2d6+x", "2d6-1-1"} {
+		if d, err := ParseDice(in); err == nil {
+			t.Errorf("ParseDice(%q) = %v, want an error", in, d)
+		}
+	}
+}
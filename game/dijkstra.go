@@ -0,0 +1,154 @@
+package game
+
+import "math"
+
+// unreachable is the cost of a tile no path leads to.
+const unreachable = math.MaxInt32
+
+// fleeFactor, in tenths, scales the distance to the player when building
+// the map for running away. Anything over ten makes a fleeing monster
+// prefer a long way round to open space over a short dead end.
+const fleeFactor = 12
+
+// dijkstraMap holds, for every tile of a level, the cost of the cheapest
+// path from it to the nearest goal, using the same step costs as astar.
+// Walking downhill from any tile follows a cheapest path, so one map serves
+// every monster heading for the same goals.
+type dijkstraMap struct {
+	width int
+	cost  []int
+}
+
+func newDijkstraMap(level *Level) *dijkstraMap {
+	dm := &dijkstraMap{}
+	dm.width = len(level.Map[0])
+	dm.cost = make([]int, dm.width*len(level.Map))
+	for i := range dm.cost {
+		dm.cost[i] = unreachable
+	}
+	return dm
+}
+
+func (dm *dijkstraMap) at(pos Pos) int {
+	if pos.X < 0 || pos.Y < 0 || pos.X >= dm.width || pos.Y*dm.width >= len(dm.cost) {
+		return unreachable
+	}
+	return dm.cost[pos.Y*dm.width+pos.X]
+}
+
+func (dm *dijkstraMap) set(pos Pos, cost int) {
+	dm.cost[pos.Y*dm.width+pos.X] = cost
+}
+
+// scan spreads the costs already on the map to every tile that passable
+// lets a path through.
+func (dm *dijkstraMap) scan(level *Level, passable func(Pos) bool) {
+	frontier := make(pqueue, 0, len(dm.cost))
+	for i, cost := range dm.cost {
+		if cost != unreachable {
+			frontier = frontier.push(Pos{i % dm.width, i / dm.width}, cost)
+		}
+	}
+	var current Pos
+	for len(frontier) > 0 {
+		frontier, current = frontier.pop()
+		for _, dir := range compass {
+			next := Pos{current.X + dir.X, current.Y + dir.Y}
+			if !passable(next) || diagonalBlocked(level, current, next) {
+				continue
+			}
+			newCost := dm.at(current) + moveCost(level, current, next)
+			if newCost < dm.at(next) {
+				dm.set(next, newCost)
+				frontier = frontier.push(next, newCost)
+			}
+		}
+	}
+}
+
+// downhill returns the free neighbour of from with the lowest cost, if it
+// is lower than that of from itself. The player's tile is never chosen.
+func (dm *dijkstraMap) downhill(level *Level, from Pos) (Pos, bool) {
+	best := from
+	bestCost := dm.at(from)
+	for _, next := range getNeighbors(level, from, true) {
+		cost := dm.at(next)
+		if next != level.Player.Pos && cost < bestCost {
+			best = next
+			bestCost = cost
+		}
+	}
+	return best, best != from
+}
+
+// flowMaps are the level's shared Dijkstra maps. They are worked out the
+// first time they are needed each turn and thrown away when the next turn
+// starts.
+type flowMaps struct {
+	toward  *dijkstraMap
+	away    *dijkstraMap
+	explore *dijkstraMap
+}
+
+// monsterPassable is where monsters can go, ignoring each other: they can't
+// open doors.
+func (level *Level) monsterPassable(pos Pos) bool {
+	return !isSolid(level, pos)
+}
+
+// explorerPassable is where the player can go: closed doors open when
+// walked into.
+func (level *Level) explorerPassable(pos Pos) bool {
+	if !inRange(level, pos) {
+		return false
+	}
+	r := level.Map[pos.Y][pos.X].Rune
+	return r != StoneWall && r != Blank
+}
+
+// towardPlayer is the map of distances to the player.
+func (level *Level) towardPlayer() *dijkstraMap {
+	if level.flow.toward == nil {
+		dm := newDijkstraMap(level)
+		dm.set(level.Player.Pos, 0)
+		dm.scan(level, level.monsterPassable)
+		level.flow.toward = dm
+	}
+	return level.flow.toward
+}
+
+// awayFromPlayer is the map fleeing monsters follow downhill. It is the
+// distance to the player turned upside down and scanned again, so that it
+// leads away from the player rather than just to the nearest far corner.
+func (level *Level) awayFromPlayer() *dijkstraMap {
+	if level.flow.away == nil {
+		toward := level.towardPlayer()
+		dm := newDijkstraMap(level)
+		for i, cost := range toward.cost {
+			if cost != unreachable {
+				dm.cost[i] = -cost * fleeFactor / 10
+			}
+		}
+		dm.scan(level, level.monsterPassable)
+		level.flow.away = dm
+	}
+	return level.flow.away
+}
+
+// towardUnexplored is the map of distances to the nearest tile the player
+// has never seen.
+func (level *Level) towardUnexplored() *dijkstraMap {
+	if level.flow.explore == nil {
+		dm := newDijkstraMap(level)
+		for y, row := range level.Map {
+			for x, tile := range row {
+				if !tile.Seen && level.explorerPassable(Pos{x, y}) {
+					dm.set(Pos{x, y}, 0)
+				}
+			}
+		}
+		dm.scan(level, level.explorerPassable)
+		level.flow.explore = dm
+	}
+	return level.flow.explore
+}
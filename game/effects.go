@@ -0,0 +1,50 @@
+package game
+
+import "strconv"
+
+// Effect is a temporary status on a character, such as being hasted. While
+// it lasts the character's speed is multiplied by Speed.
+type Effect struct {
+	Name  string
+	Speed float64
+	Ticks int
+}
+
+// speed is the character's speed with all of its effects applied.
+func (c *Character) speed() float64 {
+	speed := c.Speed
+	for _, effect := range c.Effects {
+		speed *= effect.Speed
+	}
+	if speed < minSpeed {
+		speed = minSpeed
+	}
+	return speed
+}
+
+// addEffect applies an effect, replacing any effect of the same name so
+// drinking two haste potions refreshes the duration rather than stacking.
+func (level *Level) addEffect(c *Character, effect *Effect) {
+	for i, e := range c.Effects {
+		if e.Name == effect.Name {
+			c.Effects[i] = effect
+			level.AddEvent(System, c.Name+" is "+effect.Name+" for "+strconv.Itoa(effect.Ticks)+" more ticks")
+			return
+		}
+	}
+	c.Effects = append(c.Effects, effect)
+	level.AddEvent(System, c.Name+" is "+effect.Name)
+}
+
+func (level *Level) tickEffects(c *Character) {
+	remaining := c.Effects[:0]
+	for _, effect := range c.Effects {
+		effect.Ticks--
+		if effect.Ticks > 0 {
+			remaining = append(remaining, effect)
+		} else {
+			level.AddEvent(System, c.Name+" is no longer "+effect.Name)
+		}
+	}
+	c.Effects = remaining
+}
@@ -0,0 +1,161 @@
+package game
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Slot is the part of the body an item is worn in. A character can have at
+// most one equipped item per slot.
+type Slot int
+
+const (
+	NoSlot Slot = iota
+	WeaponSlot
+	BodySlot
+	HeadSlot
+	RingSlot
+)
+
+var slotNames = map[string]Slot{
+	"none":   NoSlot,
+	"weapon": WeaponSlot,
+	"body":   BodySlot,
+	"head":   HeadSlot,
+	"ring":   RingSlot,
+}
+
+const (
+	baseHitChance = 80
+	minHitChance  = 5
+	maxHitChance  = 95
+	// critChance is the percentage of attacks that are critical hits.
+	critChance = 5
+)
+
+// Equipped returns the item worn in slot, or nil.
+func (c *Character) Equipped(slot Slot) *Item {
+	for _, item := range c.Inventory {
+		if item.Equipped && item.Slot == slot {
+			return item
+		}
+	}
+	return nil
+}
+
+// attackPower is the flat bonus added to every damage roll: a quarter of
+// the character's strength plus whatever their equipment adds.
+func (c *Character) attackPower() int {
+	power := c.Strength / 4
+	for _, item := range c.Inventory {
+		if item.Equipped {
+			power += item.Attack
+		}
+	}
+	return power
+}
+
+func (c *Character) defence() int {
+	defence := 0
+	for _, item := range c.Inventory {
+		if item.Equipped {
+			defence += item.Defence
+		}
+	}
+	return defence
+}
+
+// hitChance is the percentage chance of landing an attack on a target with
+// no evasion.
+func (c *Character) hitChance() int {
+	chance := baseHitChance + c.Accuracy
+	for _, item := range c.Inventory {
+		if item.Equipped {
+			chance += item.Accuracy
+		}
+	}
+	if chance > maxHitChance {
+		chance = maxHitChance
+	}
+	return chance
+}
+
+// damageDice is the roll for the character's equipped weapon, or their
+// natural attack when they have none.
+func (c *Character) damageDice() Dice {
+	weapon := c.Equipped(WeaponSlot)
+	if weapon != nil && weapon.Damage.Count > 0 {
+		return weapon.Damage
+	}
+	return c.Damage
+}
+
+// bonusString describes what an item adds when worn, e.g. "1d8 damage, +2
+// defence".
+func (item *Item) bonusString() string {
+	var bonuses []string
+	if item.Damage.Count > 0 {
+		bonuses = append(bonuses, item.Damage.String()+" damage")
+	}
+	if item.Attack != 0 {
+		bonuses = append(bonuses, signed(item.Attack)+" attack")
+	}
+	if item.Defence != 0 {
+		bonuses = append(bonuses, signed(item.Defence)+" defence")
+	}
+	if item.Accuracy != 0 {
+		bonuses = append(bonuses, signed(item.Accuracy)+"% to hit")
+	}
+	return strings.Join(bonuses, ", ")
+}
+
+func signed(n int) string {
+	if n >= 0 {
+		return "+" + strconv.Itoa(n)
+	}
+	return strconv.Itoa(n)
+}
+
+func (level *Level) putOn(c *Character, item *Item) {
+	old := c.Equipped(item.Slot)
+	if old != nil {
+		level.takeOff(c, old)
+	}
+	item.Equipped = true
+	event := c.Name + " equipped " + item.Name
+	bonus := item.bonusString()
+	if bonus != "" {
+		event += " (" + bonus + ")"
+	}
+	level.AddEvent(System, event)
+}
+
+func (level *Level) takeOff(c *Character, item *Item) {
+	item.Equipped = false
+	level.AddEvent(System, c.Name+" removed "+item.Name)
+}
+
+func (level *Level) equip() {
+	p := level.Player
+	item := p.SelectedItem()
+	switch {
+	case item == nil:
+		level.AddEvent(System, "You have nothing to equip")
+	case item.Slot == NoSlot:
+		level.AddEvent(System, p.Name+" can't equip "+item.Name)
+	case item.Equipped:
+		level.AddEvent(System, item.Name+" is already equipped")
+	default:
+		level.putOn(&p.Character, item)
+	}
+}
+
+func (level *Level) unequip() {
+	p := level.Player
+	item := p.SelectedItem()
+	if item == nil || !item.Equipped {
+		level.AddEvent(System, "That item is not equipped")
+		return
+	}
+	level.takeOff(&p.Character, item)
+}
@@ -0,0 +1,381 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FOV is a field of view algorithm. Compute calls visit once for every tile
+// that can be seen from origin within radius tiles, including origin
+// itself. Walls and closed doors that can be seen are visited too.
+type FOV interface {
+	Name() string
+	Compute(level *Level, origin Pos, radius int, visit func(Pos))
+}
+
+// defaultFOV is used by new games and by saves that don't name an algorithm.
+var defaultFOV FOV = ShadowcastFOV{}
+
+var fovNames = map[string]FOV{
+	"ray":        RayFOV{},
+	"shadowcast": ShadowcastFOV{},
+	"permissive": PermissiveFOV{},
+}
+
+// ParseFOV looks up a field of view algorithm by name: ray, shadowcast or
+// permissive.
+func ParseFOV(name string) (FOV, error) {
+	fov, ok := fovNames[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown field of view %q", name)
+	}
+	return fov, nil
+}
+
+// SetFOV changes the field of view algorithm used by the player and the
+// monsters, and recomputes what the player can see.
+func (game *Game) SetFOV(fov FOV) {
+	game.FOV = fov
+	game.CurrentLevel.lineOfSight()
+}
+
+// inSight reports whether pos is in range and within radius of origin.
+func inSight(level *Level, origin, pos Pos, radius int) bool {
+	return inRange(level, pos) && distSq(origin, pos) <= radius*radius
+}
+
+// RayFOV casts a Bresenham ray to every tile on the edge of and inside the
+// circle. It is cheap but not symmetric: a monster may see the player
+// without the player seeing it.
+type RayFOV struct{}
+
+func (RayFOV) Name() string { return "ray" }
+
+func (RayFOV) Compute(level *Level, origin Pos, radius int, visit func(Pos)) {
+	seen := make(map[Pos]bool)
+	for y := origin.Y - radius; y <= origin.Y+radius; y++ {
+		for x := origin.X - radius; x <= origin.X+radius; x++ {
+			if distSq(origin, Pos{x, y}) > radius*radius {
+				continue
+			}
+			castRay(origin, Pos{x, y}, func(pos Pos) bool {
+				if !inRange(level, pos) {
+					return false
+				}
+				if !seen[pos] {
+					seen[pos] = true
+					visit(pos)
+				}
+				return canSeeThrough(level, pos)
+			})
+		}
+	}
+	if !seen[origin] && inRange(level, origin) {
+		visit(origin)
+	}
+}
+
+// ShadowcastFOV is symmetric recursive shadowcasting: a floor tile is
+// visible from another exactly when the other is visible from it, and
+// pillars cast clean shadows. It scans the four quadrants around the
+// origin row by row, narrowing the range of visible slopes at each wall.
+type ShadowcastFOV struct{}
+
+func (ShadowcastFOV) Name() string { return "shadowcast" }
+
+func (ShadowcastFOV) Compute(level *Level, origin Pos, radius int, visit func(Pos)) {
+	if !inRange(level, origin) {
+		return
+	}
+	seen := make(map[Pos]bool)
+	visit(origin)
+	seen[origin] = true
+	for quadrant := 0; quadrant < 4; quadrant++ {
+		s := &shadowcaster{level, origin, radius, quadrant, visit, seen}
+		s.scan(1, slope{-1, 1}, slope{1, 1})
+	}
+}
+
+// slope is the fraction num/den, kept exact so that the symmetry test
+// doesn't suffer from rounding. den is always positive.
+type slope struct {
+	num, den int
+}
+
+type shadowcaster struct {
+	level    *Level
+	origin   Pos
+	radius   int
+	quadrant int
+	visit    func(Pos)
+	seen     map[Pos]bool
+}
+
+// transform turns a row depth and column within the quadrant into a map
+// position. Quadrants 0 to 3 look north, east, south and west.
+func (s *shadowcaster) transform(depth, col int) Pos {
+	switch s.quadrant {
+	case 0:
+		return Pos{s.origin.X + col, s.origin.Y - depth}
+	case 1:
+		return Pos{s.origin.X + depth, s.origin.Y + col}
+	case 2:
+		return Pos{s.origin.X + col, s.origin.Y + depth}
+	default:
+		return Pos{s.origin.X - depth, s.origin.Y + col}
+	}
+}
+
+func (s *shadowcaster) reveal(pos Pos) {
+	if !s.seen[pos] && inSight(s.level, s.origin, pos, s.radius) {
+		s.seen[pos] = true
+		s.visit(pos)
+	}
+}
+
+func (s *shadowcaster) scan(depth int, start, end slope) {
+	if depth > s.radius {
+		return
+	}
+	// Columns from depth*start rounded half up to depth*end rounded half down.
+	minCol := floorDiv(2*depth*start.num+start.den, 2*start.den)
+	maxCol := -floorDiv(-(2*depth*end.num - end.den), 2*end.den)
+
+	first := true
+	prevWall := false
+	for col := minCol; col <= maxCol; col++ {
+		pos := s.transform(depth, col)
+		wall := !canSeeThrough(s.level, pos)
+		symmetric := col*start.den >= depth*start.num && col*end.den <= depth*end.num
+		if wall || symmetric {
+			s.reveal(pos)
+		}
+		if !first && prevWall && !wall {
+			start = slope{2*col - 1, 2 * depth}
+		}
+		if !first && !prevWall && wall {
+			s.scan(depth+1, start, slope{2*col - 1, 2 * depth})
+		}
+		prevWall = wall
+		first = false
+	}
+	if !first && !prevWall {
+		s.scan(depth+1, start, end)
+	}
+}
+
+func floorDiv(a, b int) int {
+	q := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		q--
+	}
+	return q
+}
+
+// PermissiveFOV is precise permissive field of view: a tile is visible if
+// any line from anywhere in the origin tile reaches anywhere in it without
+// crossing an opaque tile. It sees further round corners and pillars than
+// shadowcasting, and is symmetric too. Each quadrant is walked one diagonal
+// at a time, keeping a list of views, each the gap between a shallow line
+// and a steep line. Walls bend the lines round the corners they bump into,
+// and a wall in the middle of a view splits it in two.
+type PermissiveFOV struct{}
+
+func (PermissiveFOV) Name() string { return "permissive" }
+
+func (PermissiveFOV) Compute(level *Level, origin Pos, radius int, visit func(Pos)) {
+	if !inRange(level, origin) {
+		return
+	}
+	seen := make(map[Pos]bool)
+	visit(origin)
+	seen[origin] = true
+
+	// How far each quadrant reaches, stopping at the radius or the edge of
+	// the map.
+	extent := func(space int) int {
+		if space > radius {
+			return radius
+		}
+		return space
+	}
+	left, right := extent(origin.X), extent(len(level.Map[0])-origin.X-1)
+	up, down := extent(origin.Y), extent(len(level.Map)-origin.Y-1)
+
+	quadrants := []struct{ dx, dy, extentX, extentY int }{
+		{1, 1, right, down},
+		{1, -1, right, up},
+		{-1, -1, left, up},
+		{-1, 1, left, down},
+	}
+	for _, q := range quadrants {
+		p := &permissive{level, origin, radius, q.dx, q.dy, visit, seen, nil}
+		p.scan(q.extentX, q.extentY)
+	}
+}
+
+// permissiveLine runs from (xi, yi) to (xf, yf) in a quadrant's own
+// coordinates, where the origin tile is the square from (0, 0) to (1, 1).
+type permissiveLine struct {
+	xi, yi, xf, yf int
+}
+
+// relativeSlope is positive for points below the line, negative for points
+// above it and 0 for points on it.
+func (l permissiveLine) relativeSlope(x, y int) int {
+	return (l.yf-l.yi)*(l.xf-x) - (l.xf-l.xi)*(l.yf-y)
+}
+
+func (l permissiveLine) isBelow(x, y int) bool           { return l.relativeSlope(x, y) > 0 }
+func (l permissiveLine) isBelowOrContains(x, y int) bool { return l.relativeSlope(x, y) >= 0 }
+func (l permissiveLine) isAbove(x, y int) bool           { return l.relativeSlope(x, y) < 0 }
+func (l permissiveLine) isAboveOrContains(x, y int) bool { return l.relativeSlope(x, y) <= 0 }
+func (l permissiveLine) contains(x, y int) bool          { return l.relativeSlope(x, y) == 0 }
+
+func (l permissiveLine) collinear(other permissiveLine) bool {
+	return l.contains(other.xi, other.yi) && l.contains(other.xf, other.yf)
+}
+
+// bump is a wall corner a view's line has been bent round. Each view keeps
+// the bumps on each side as a list, newest first, which is never changed
+// once made, so views split in two can share it.
+type bump struct {
+	x, y   int
+	parent *bump
+}
+
+// permissiveView is the gap between a shallow line, below which nothing can
+// be seen, and a steep line, above which nothing can be seen.
+type permissiveView struct {
+	shallow, steep         permissiveLine
+	shallowBump, steepBump *bump
+}
+
+type permissive struct {
+	level  *Level
+	origin Pos
+	radius int
+	dx, dy int
+	visit  func(Pos)
+	seen   map[Pos]bool
+	views  []*permissiveView
+}
+
+// scan walks the quadrant one diagonal at a time, outwards from the
+// origin, and along each diagonal from the shallow end to the steep end so
+// the views, kept in the same order, are met one after another.
+func (p *permissive) scan(extentX, extentY int) {
+	p.views = []*permissiveView{{
+		shallow: permissiveLine{0, 1, extentX, 0},
+		steep:   permissiveLine{1, 0, 0, extentY},
+	}}
+	for i := 1; i <= extentX+extentY && len(p.views) > 0; i++ {
+		startJ := i - extentX
+		if startJ < 0 {
+			startJ = 0
+		}
+		maxJ := i
+		if maxJ > extentY {
+			maxJ = extentY
+		}
+		view := 0
+		for j := startJ; j <= maxJ && view < len(p.views); j++ {
+			view = p.visitTile(i-j, j, view)
+		}
+	}
+}
+
+// visitTile visits the tile at (x, y) in the quadrant if any view reaches
+// it, starting from view, and narrows, splits or removes that view if the
+// tile is opaque. It returns the view to start from for the next tile.
+func (p *permissive) visitTile(x, y, view int) int {
+	// The corners a line must pass to reach into the tile.
+	topLeftX, topLeftY := x, y+1
+	bottomRightX, bottomRightY := x+1, y
+
+	for view < len(p.views) && p.views[view].steep.isBelowOrContains(bottomRightX, bottomRightY) {
+		view++
+	}
+	if view == len(p.views) || p.views[view].shallow.isAboveOrContains(topLeftX, topLeftY) {
+		return view
+	}
+
+	pos := Pos{p.origin.X + x*p.dx, p.origin.Y + y*p.dy}
+	if !p.seen[pos] && inSight(p.level, p.origin, pos, p.radius) {
+		p.seen[pos] = true
+		p.visit(pos)
+	}
+	if canSeeThrough(p.level, pos) {
+		return view
+	}
+
+	v := p.views[view]
+	shallowAbove := v.shallow.isAbove(bottomRightX, bottomRightY)
+	steepBelow := v.steep.isBelow(topLeftX, topLeftY)
+	switch {
+	case shallowAbove && steepBelow:
+		// The tile fills the view.
+		p.removeView(view)
+	case shallowAbove:
+		p.addShallowBump(v, topLeftX, topLeftY)
+		p.checkView(view)
+	case steepBelow:
+		p.addSteepBump(v, bottomRightX, bottomRightY)
+		p.checkView(view)
+	default:
+		// The tile is in the middle of the view: the part below it and the
+		// part above it carry on separately.
+		below := *v
+		p.views = append(p.views, nil)
+		copy(p.views[view+1:], p.views[view:])
+		p.views[view] = &below
+		p.addSteepBump(&below, bottomRightX, bottomRightY)
+		p.addShallowBump(v, topLeftX, topLeftY)
+		if p.checkView(view) {
+			p.checkView(view + 1)
+		} else {
+			p.checkView(view)
+		}
+	}
+	return view
+}
+
+// addShallowBump bends the shallow line up to pass above the corner at
+// (x, y), keeping it above every bump on the steep side.
+func (p *permissive) addShallowBump(v *permissiveView, x, y int) {
+	v.shallow.xf, v.shallow.yf = x, y
+	v.shallowBump = &bump{x, y, v.shallowBump}
+	for b := v.steepBump; b != nil; b = b.parent {
+		if v.shallow.isAbove(b.x, b.y) {
+			v.shallow.xi, v.shallow.yi = b.x, b.y
+		}
+	}
+}
+
+// addSteepBump bends the steep line down to pass below the corner at
+// (x, y), keeping it below every bump on the shallow side.
+func (p *permissive) addSteepBump(v *permissiveView, x, y int) {
+	v.steep.xf, v.steep.yf = x, y
+	v.steepBump = &bump{x, y, v.steepBump}
+	for b := v.shallowBump; b != nil; b = b.parent {
+		if v.steep.isBelow(b.x, b.y) {
+			v.steep.xi, v.steep.yi = b.x, b.y
+		}
+	}
+}
+
+// checkView removes the view if its lines have closed up into one that
+// starts at a corner of the origin tile, so that nothing more can be seen
+// through it, and reports whether the view is still there.
+func (p *permissive) checkView(view int) bool {
+	v := p.views[view]
+	if v.shallow.collinear(v.steep) && (v.shallow.contains(0, 1) || v.shallow.contains(1, 0)) {
+		p.removeView(view)
+		return false
+	}
+	return true
+}
+
+func (p *permissive) removeView(view int) {
+	p.views = append(p.views[:view], p.views[view+1:]...)
+}
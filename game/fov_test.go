@@ -0,0 +1,40 @@
+package game
+
+import "testing"
+
+// TestFOVSymmetry checks on every shipped map that one floor tile sees
+// another exactly when the other sees it back.
+func TestFOVSymmetry(t *testing.T) {
+	g, err := NewGame(0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const radius = 8
+	for _, fov := range []FOV{ShadowcastFOV{}, PermissiveFOV{}} {
+		for name, level := range g.Levels {
+			var floors []Pos
+			for y, row := range level.Map {
+				for x := range row {
+					if canSeeThrough(level, Pos{x, y}) {
+						floors = append(floors, Pos{x, y})
+					}
+				}
+			}
+			sees := make(map[Pos]map[Pos]bool)
+			for _, from := range floors {
+				seen := make(map[Pos]bool)
+				fov.Compute(level, from, radius, func(pos Pos) {
+					seen[pos] = true
+				})
+				sees[from] = seen
+			}
+			for _, from := range floors {
+				for to := range sees[from] {
+					if canSeeThrough(level, to) && !sees[to][from] {
+						t.Errorf("%s on %s: %v sees %v but not the other way round", fov.Name(), name, from, to)
+					}
+				}
+			}
+		}
+	}
+}
@@ -8,8 +8,10 @@ import (
 	"strings"
 
 	"math"
+	"math/rand"
 	"os"
 	"strconv"
+	"time"
 )
 
 type Game struct {
@@ -17,6 +19,58 @@ type Game struct {
 	InputChan    chan *Input
 	Levels       map[string]*Level
 	CurrentLevel *Level
+	// Seed is the seed of the game's random number generator. Starting a
+	// new game with the same seed and inputs replays it exactly.
+	Seed int64
+	// FOV is the field of view algorithm used by the player and monsters.
+	FOV       FOV
+	catalogue *catalogue
+	source    *countingSource
+	rng       *rand.Rand
+	travel    *travel
+	// Messages is the history of every event in the game, up to
+	// MaxMessages of them.
+	Messages    []Message
+	MaxMessages int
+	// Adventure counts the runs played in this game, going up by one every
+	// time it is restarted, so the history can tell them apart.
+	Adventure int
+}
+
+// catalogue holds the monster and item definitions loaded from game/data.
+type catalogue struct {
+	monsters map[rune]*MonsterKind
+	items    map[rune]*ItemKind
+}
+
+// mapGlyphs are the characters map files use for terrain and the player,
+// which monster and item runes may not share.
+var mapGlyphs = []rune{' ', StoneWall, DirtFloor, Water, Rubble, Mud, CloseDoor, OpenDoor, UpStair, DownStair, '@'}
+
+func checkCatalogueRune(r rune) error {
+	for _, glyph := range mapGlyphs {
+		if r == glyph {
+			return fmt.Errorf("rune %q is already used by the map files", r)
+		}
+	}
+	return nil
+}
+
+func loadCatalogue() (*catalogue, error) {
+	monsters, err := loadMonsterKinds(monsterFile)
+	if err != nil {
+		return nil, err
+	}
+	items, err := loadItemKinds(itemFile)
+	if err != nil {
+		return nil, err
+	}
+	for r := range items {
+		if _, exists := monsters[r]; exists {
+			return nil, fmt.Errorf("%s, %s: rune %q is used by both a monster and an item", monsterFile, itemFile, r)
+		}
+	}
+	return &catalogue{monsters, items}, nil
 }
 
 type LevelPos struct {
@@ -24,18 +78,59 @@ type LevelPos struct {
 	Pos
 }
 
-func NewGame(numWindows int) *Game {
+// NewGame loads the monster and item files, the maps and the world file,
+// and returns an error naming the file and line of anything it can't read.
+func NewGame(numWindows int, seed int64) (*Game, error) {
 	levelChans := make([]chan *Level, numWindows)
 	for i := range levelChans {
 		levelChans[i] = make(chan *Level)
 	}
 	inputChan := make(chan *Input)
-	levels := loadLevels()
+	cat, err := loadCatalogue()
+	if err != nil {
+		return nil, err
+	}
 
-	game := &Game{levelChans, inputChan, levels, nil}
-	game.loadWorldFile()
+	game := &Game{LevelChans: levelChans, InputChan: inputChan, Seed: seed, FOV: defaultFOV, MaxMessages: DefaultMaxMessages, Adventure: 1}
+	game.catalogue = cat
+	game.seedRNG(seed, 0)
+	err = game.buildWorld()
+	if err != nil {
+		return nil, err
+	}
+	game.CurrentLevel.AddEvent(System, "Game seed "+strconv.FormatInt(seed, 10))
+	return game, nil
+}
+
+// buildWorld loads every level from disk and links them up, replacing any
+// levels the game already had.
+func (game *Game) buildWorld() error {
+	levels, err := loadLevels(game.catalogue, newPlayer())
+	if err != nil {
+		return err
+	}
+	game.setLevels(levels)
+	err = game.loadWorldFile()
+	if err != nil {
+		return err
+	}
 	game.CurrentLevel.lineOfSight()
-	return game
+	return nil
+}
+
+func (game *Game) seedRNG(seed int64, draws uint64) {
+	game.Seed = seed
+	game.source = newCountingSource(seed, draws)
+	game.rng = rand.New(game.source)
+}
+
+// setLevels replaces the game's levels, pointing each one back at the game
+// so it can reach the shared random number generator.
+func (game *Game) setLevels(levels map[string]*Level) {
+	game.Levels = levels
+	for _, level := range levels {
+		level.game = game
+	}
 }
 
 type InputType int
@@ -49,11 +144,94 @@ const (
 	QuitGame
 	CloseWindow
 	Search //temp
+
+	// Inputs added since are appended here, grouped by purpose, so that the
+	// values above never change.
+	UpLeft
+	UpRight
+	DownLeft
+	DownRight
+	Explore
+	TravelTo
+	TravelUpStair
+	TravelDownStair
+	Attack
+	Inspect
+	PickUp
+	Drop
+	Use
+	SelectNext
+	SelectPrev
+	Equip
+	Unequip
+	SaveGame
+	LoadGame
+	Restart
 )
 
+var inputNames = map[string]InputType{
+	"up":        Up,
+	"down":      Down,
+	"left":      Left,
+	"right":     Right,
+	"quit":      QuitGame,
+	"save":      SaveGame,
+	"load":      LoadGame,
+	"pickup":    PickUp,
+	"drop":      Drop,
+	"use":       Use,
+	"next":      SelectNext,
+	"prev":      SelectPrev,
+	"equip":     Equip,
+	"unequip":   Unequip,
+	"restart":   Restart,
+	"upleft":    UpLeft,
+	"upright":   UpRight,
+	"downleft":  DownLeft,
+	"downright": DownRight,
+	"explore":   Explore,
+	"travel":    TravelTo,
+	"upstair":   TravelUpStair,
+	"downstair": TravelDownStair,
+	"attack":    Attack,
+	"inspect":   Inspect,
+}
+
+// ParseInputType looks up an input by the name used in scripts, e.g. "up".
+func ParseInputType(name string) (InputType, error) {
+	typ, ok := inputNames[strings.ToLower(name)]
+	if !ok {
+		return None, fmt.Errorf("unknown input %q", name)
+	}
+	return typ, nil
+}
+
+// String returns the name ParseInputType reads for t.
+func (t InputType) String() string {
+	for name, typ := range inputNames {
+		if typ == t {
+			return name
+		}
+	}
+	return "input " + strconv.Itoa(int(t))
+}
+
+// NeedsTarget reports whether an input of type t is aimed at the position
+// in its Target.
+func (t InputType) NeedsTarget() bool {
+	switch t {
+	case TravelTo, Attack, Inspect:
+		return true
+	}
+	return false
+}
+
 type Input struct {
 	Typ          InputType
 	LevelChannel chan *Level
+	// Target is where a TravelTo input goes, and the tile an Attack or
+	// Inspect input is aimed at.
+	Target Pos
 }
 
 type Tile struct {
@@ -77,6 +255,15 @@ const (
 type Pos struct {
 	X, Y int
 }
+
+// posLess orders positions row by row, top to bottom.
+func posLess(a, b Pos) bool {
+	if a.Y != b.Y {
+		return a.Y < b.Y
+	}
+	return a.X < b.X
+}
+
 type Entity struct {
 	Pos
 	Name string
@@ -86,123 +273,291 @@ type Entity struct {
 type Character struct {
 	Entity
 	Hitpoints    int
+	MaxHitpoints int
 	Strength     int
 	Speed        float64
 	ActionPoints float64
 	SightRange   int
+	Effects      []*Effect
+	Accuracy     int
+	Evasion      int
+	Damage       Dice
+	Inventory    []*Item
 }
 type Player struct {
 	Character
+	Selected int
+	Turns    int
+	Kills    int
+	KilledBy string
+	// Stealth is taken off the noise of everything the player does.
+	Stealth int
 }
 type Level struct {
+	Name     string
 	Map      [][]Tile
 	Player   *Player
 	Monsters map[Pos]*Monster
+	Items    map[Pos][]*Item
 	Portals  map[Pos]*LevelPos
 	Debug    map[Pos]bool
 	Events   []string
 	EventPos int
+	// eventCount counts every event ever added, so that travel can tell
+	// when something new has happened.
+	eventCount int
+	// Noise is how far the loudest sound the player made this turn carries,
+	// and NoisePos where it was made.
+	Noise    int
+	NoisePos Pos
+	noiseID  int
+	flow     flowMaps
+	game     *Game
+	// history is the message history a snapshot was taken with.
+	history []Message
 }
 
+// Attack resolves one blow from c1 against c2. The blow lands if a d100
+// roll is under c1's hit chance less c2's evasion; a landed blow rolls c1's
+// damage dice, twice on a critical hit, and c2's armour soaks up part of it.
 func (level *Level) Attack(c1, c2 *Character) {
-	c1.ActionPoints--
-	c1AttackPower := c1.Strength
-	c2.Hitpoints -= c1AttackPower
+	rng := level.game.rng
+	chance := c1.hitChance() - c2.Evasion
+	if chance < minHitChance {
+		chance = minHitChance
+	}
+	roll := rng.Intn(100)
+	if roll >= chance {
+		level.AddEvent(Combat, c1.Name+" Missed "+c2.Name)
+		return
+	}
+
+	dice := c1.damageDice()
+	damage := dice.roll(rng)
+	critical := roll < critChance
+	if critical {
+		damage += dice.roll(rng)
+	}
+	damage += c1.attackPower() - c2.defence()
+	if damage < 0 {
+		damage = 0
+	}
+	c2.Hitpoints -= damage
 
-	if c2.Hitpoints > 0 {
-		level.AddEvent(c1.Name + " Attacked " + c2.Name + " for " + strconv.Itoa(c1AttackPower))
+	verb := " Attacked "
+	if critical {
+		verb = " Critically hit "
+	}
+	if damage == 0 {
+		level.AddEvent(Combat, c2.Name+" Blocked "+c1.Name+"'s attack")
+	} else if c2.Hitpoints > 0 {
+		level.AddEvent(Combat, c1.Name+verb+c2.Name+" for "+strconv.Itoa(damage))
 	} else {
-		level.AddEvent(c1.Name + " Killed " + c2.Name)
+		level.AddEvent(Combat, c1.Name+" Killed "+c2.Name)
+		level.recordKill(c1, c2)
 	}
 }
 
-func (level *Level) AddEvent(event string) {
+// AddEvent shows event in the level's recent events and records it in the
+// game's message history.
+func (level *Level) AddEvent(category Category, event string) {
+	if level.game != nil {
+		level.game.addMessage(Message{level.game.Adventure, level.Player.Turns, category, level.Name, event})
+	}
 	level.Events[level.EventPos] = event
+	level.eventCount++
 	level.EventPos++
 	if level.EventPos == len(level.Events) {
 		level.EventPos = 0
 	}
 }
-func (game *Game) loadWorldFile() {
-	file, err := os.Open("game/maps/world.txt")
+
+// String draws the whole level as text, one row per line, with items,
+// monsters and the player on top of the tiles. Visibility is ignored.
+func (level *Level) String() string {
+	var sb strings.Builder
+	for y, row := range level.Map {
+		line := make([]rune, len(row))
+		for x, tile := range row {
+			pos := Pos{x, y}
+			r := tile.Rune
+			if tile.OverlayRune != Blank {
+				r = tile.OverlayRune
+			}
+			if items := level.Items[pos]; len(items) > 0 {
+				r = items[len(items)-1].Rune
+			}
+			if monster, exists := level.Monsters[pos]; exists {
+				r = monster.Rune
+			}
+			if level.Player.Pos == pos {
+				r = level.Player.Rune
+			}
+			if r == Blank {
+				r = ' '
+			}
+			line[x] = r
+		}
+		sb.WriteString(strings.TrimRight(string(line), " "))
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+const worldFile = "game/maps/world.txt"
+
+// loadWorldFile reads game/maps/world.txt. The first row names the starting
+// level. Each row after that is either
+//
+//	generate, name, width, height, seed
+//
+// which adds a procedurally generated level, or a portal
+//
+//	fromLevel, x, y, toLevel, x, y
+//
+// where either position may instead be the word up or down to mean the
+// position of that level's only up or down stair.
+func (game *Game) loadWorldFile() error {
+	file, err := os.Open(worldFile)
 	if err != nil {
-		panic(err)
+		return err
 	}
+	defer file.Close()
 	csvReader := csv.NewReader(file)
 	csvReader.FieldsPerRecord = -1
 	csvReader.TrimLeadingSpace = true
 	rows, err := csvReader.ReadAll()
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("%s: %v", worldFile, err)
 	}
 
 	for rowIndex, row := range rows {
+		line := rowIndex + 1
 		//Set First Row to First Level
 		if rowIndex == 0 {
 			game.CurrentLevel = game.Levels[row[0]]
 			if game.CurrentLevel == nil {
-				fmt.Println("couldnt find starting level")
-				panic(nil)
+				return fmt.Errorf("%s:%d: unknown starting level %q", worldFile, line, row[0])
 			}
 			continue
 		}
-		levelWithPortal := game.Levels[row[0]]
-		if levelWithPortal == nil {
-			fmt.Println("couldnt find level 1 name")
-			panic(nil)
+		if row[0] == "generate" {
+			err = game.generateFromRow(row)
+			if err != nil {
+				return fmt.Errorf("%s:%d: %v", worldFile, line, err)
+			}
+			continue
 		}
-		x, err := strconv.ParseInt(row[1], 10, 64)
+
+		levelWithPortal, pos, next, err := game.parsePortalEnd(row, 0)
 		if err != nil {
-			panic(err)
+			return fmt.Errorf("%s:%d: %v", worldFile, line, err)
 		}
-		y, err := strconv.ParseInt(row[2], 10, 64)
+		levelToTeleportTo, posToTeleportTo, next, err := game.parsePortalEnd(row, next)
 		if err != nil {
-			panic(err)
+			return fmt.Errorf("%s:%d: %v", worldFile, line, err)
 		}
-		pos := Pos{int(x), int(y)}
-
-		levelToTeleportTo := game.Levels[row[3]]
-		if levelToTeleportTo == nil {
-			fmt.Println("couldnt find level 2 name")
-			panic(nil)
+		if next != len(row) {
+			return fmt.Errorf("%s:%d: unexpected fields after portal", worldFile, line)
 		}
-		x, err = strconv.ParseInt(row[4], 10, 64)
-		if err != nil {
-			panic(err)
+		levelWithPortal.Portals[pos] = &LevelPos{levelToTeleportTo, posToTeleportTo}
+	}
+	return nil
+}
+
+// parsePortalEnd reads a level name followed by either x, y or a stair
+// keyword starting at row[i], returning the index of the next field.
+func (game *Game) parsePortalEnd(row []string, i int) (*Level, Pos, int, error) {
+	if i+1 >= len(row) {
+		return nil, Pos{}, i, fmt.Errorf("portal is missing fields")
+	}
+	level := game.Levels[row[i]]
+	if level == nil {
+		return nil, Pos{}, i, fmt.Errorf("unknown level %q", row[i])
+	}
+	switch row[i+1] {
+	case "up", "down":
+		stair := UpStair
+		if row[i+1] == "down" {
+			stair = DownStair
 		}
-		y, err = strconv.ParseInt(row[5], 10, 64)
-		if err != nil {
-			panic(err)
+		pos, err := level.findStair(stair)
+		return level, pos, i + 2, err
+	}
+	if i+2 >= len(row) {
+		return nil, Pos{}, i, fmt.Errorf("portal is missing fields")
+	}
+	x, err := strconv.Atoi(row[i+1])
+	if err != nil {
+		return nil, Pos{}, i, err
+	}
+	y, err := strconv.Atoi(row[i+2])
+	if err != nil {
+		return nil, Pos{}, i, err
+	}
+	return level, Pos{x, y}, i + 3, nil
+}
+
+func (level *Level) findStair(stair rune) (Pos, error) {
+	var found []Pos
+	for y, row := range level.Map {
+		for x, tile := range row {
+			if tile.OverlayRune == stair {
+				found = append(found, Pos{x, y})
+			}
 		}
-		posToTeleportTo := Pos{int(x), int(y)}
-		levelWithPortal.Portals[pos] = &LevelPos{levelToTeleportTo, posToTeleportTo}
 	}
+	if len(found) != 1 {
+		return Pos{}, fmt.Errorf("level %s has %d %q stairs, want exactly one", level.Name, len(found), stair)
+	}
+	return found[0], nil
 }
 
-func loadLevels() map[string]*Level {
+func newPlayer() *Player {
 	player := &Player{}
 	player.Strength = 20
+	player.Damage = Dice{2, 8, 0}
+	player.Evasion = 5
 	player.Hitpoints = 20
+	player.MaxHitpoints = 20
 	player.Name = "GoMan"
 	player.Rune = '@'
 	player.Speed = 1.0
-	player.ActionPoints = 0.0
+	player.ActionPoints = actionCost
 	player.SightRange = 10
+	player.Stealth = 2
+	return player
+}
+
+func newLevel(name string, width, height int, player *Player) *Level {
+	level := &Level{}
+	level.Name = name
+	level.Debug = make(map[Pos]bool)
+	level.Events = make([]string, 10)
+	level.Player = player
+	level.Map = make([][]Tile, height)
+	level.Monsters = make(map[Pos]*Monster)
+	level.Items = make(map[Pos][]*Item)
+	level.Portals = make(map[Pos]*LevelPos)
+	for i := range level.Map {
+		level.Map[i] = make([]Tile, width)
+	}
+	return level
+}
 
+func loadLevels(cat *catalogue, player *Player) (map[string]*Level, error) {
 	levels := make(map[string]*Level)
 
 	filesnames, err := filepath.Glob("game/maps/*.map")
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 	for _, filename := range filesnames {
 
-		extIndex := strings.LastIndex(filename, ".map")
-		lastSlashIndex := strings.LastIndex(filename, "\\")
-		levelName := filename[lastSlashIndex+1 : extIndex]
+		levelName := strings.TrimSuffix(filepath.Base(filename), ".map")
 		file, err := os.Open(filename)
 		if err != nil {
-			panic(err)
+			return nil, err
 		}
 		defer file.Close()
 		scanner := bufio.NewScanner(file)
@@ -216,16 +571,7 @@ func loadLevels() map[string]*Level {
 			}
 			index++
 		}
-		level := &Level{}
-		level.Debug = make(map[Pos]bool)
-		level.Events = make([]string, 10)
-		level.Player = player
-		level.Map = make([][]Tile, len(levelLines))
-		level.Monsters = make(map[Pos]*Monster)
-		level.Portals = make(map[Pos]*LevelPos)
-		for i := range level.Map {
-			level.Map[i] = make([]Tile, longestRow)
-		}
+		level := newLevel(levelName, longestRow, len(levelLines), player)
 
 		for y := 0; y < len(level.Map); y++ {
 			line := levelLines[y]
@@ -239,6 +585,12 @@ func loadLevels() map[string]*Level {
 					t.Rune = StoneWall
 				case '.':
 					t.Rune = DirtFloor
+				case '~':
+					t.Rune = Water
+				case ':':
+					t.Rune = Rubble
+				case ',':
+					t.Rune = Mud
 				case '|':
 					t.OverlayRune = CloseDoor
 					t.Rune = Pending
@@ -255,14 +607,18 @@ func loadLevels() map[string]*Level {
 					level.Player.X = x
 					level.Player.Y = y
 					t.Rune = Pending
-				case 'R':
-					level.Monsters[Pos{x, y}] = NewRat(Pos{x, y})
-					t.Rune = Pending
-				case 'S':
-					level.Monsters[Pos{x, y}] = NewSpider(Pos{x, y})
-					t.Rune = Pending
 				default:
-					panic("Invalid Character in map")
+					if kind, exists := cat.items[c]; exists {
+						level.Items[Pos{x, y}] = append(level.Items[Pos{x, y}], NewItem(kind, Pos{x, y}))
+						t.Rune = Pending
+						break
+					}
+					kind, exists := cat.monsters[c]
+					if !exists {
+						return nil, fmt.Errorf("%s:%d:%d: unknown map character %q", filename, y+1, x+1, c)
+					}
+					level.Monsters[Pos{x, y}] = NewMonster(kind, Pos{x, y})
+					t.Rune = Pending
 				}
 				level.Map[y][x] = t
 			}
@@ -278,7 +634,7 @@ func loadLevels() map[string]*Level {
 		}
 		levels[levelName] = level
 	}
-	return levels
+	return levels, nil
 
 }
 
@@ -306,12 +662,15 @@ func checkDoor(level *Level, pos Pos) {
 	t := level.Map[pos.Y][pos.X]
 	if t.OverlayRune == CloseDoor {
 		level.Map[pos.Y][pos.X].OverlayRune = OpenDoor
+		level.makeNoise(pos, noiseDoor)
 	}
 	level.lineOfSight()
 
 }
 
-func (game *Game) Move(to Pos) {
+// Move puts the player on to, or through the portal there, and returns the
+// energy it cost.
+func (game *Game) Move(to Pos) float64 {
 	level := game.CurrentLevel
 	player := game.CurrentLevel.Player
 	levelAndPos := level.Portals[to]
@@ -319,16 +678,12 @@ func (game *Game) Move(to Pos) {
 		game.CurrentLevel = levelAndPos.Level
 		game.CurrentLevel.Player.Pos = levelAndPos.Pos
 		game.CurrentLevel.lineOfSight()
-	} else {
-		player.Pos = to
-		for y, row := range level.Map {
-			for x := range row {
-				level.Map[y][x].Visible = false
-			}
-		}
-
-		level.lineOfSight()
+		return actionCost
 	}
+	player.Pos = to
+	level.makeNoise(to, noiseMove)
+	level.lineOfSight()
+	return stepEnergy(level, to)
 }
 
 func canSeeThrough(level *Level, pos Pos) bool {
@@ -348,69 +703,117 @@ func canSeeThrough(level *Level, pos Pos) bool {
 	return false
 }
 
-func (game *Game) resolveMovement(pos Pos) {
+// resolveMovement attacks, walks to or opens whatever is at pos and returns
+// the energy it cost.
+func (game *Game) resolveMovement(pos Pos) float64 {
 	level := game.CurrentLevel
 	monster, exists := level.Monsters[pos]
 	if exists {
+		level.makeNoise(pos, noiseAttack)
 		level.Attack(&level.Player.Character, &monster.Character)
 		if monster.Hitpoints <= 0 {
 			delete(level.Monsters, monster.Pos)
 		}
-		if level.Player.Hitpoints <= 0 {
-			level.AddEvent("You have died")
-		}
 	} else if canWalk(level, pos) {
-		game.Move(pos)
+		return game.Move(pos)
 	} else {
 		checkDoor(level, pos)
 	}
+	return actionCost
 }
-func (game *Game) handleInput(input *Input) {
+
+// handleInput carries out the player's input and returns the energy it
+// cost, or 0 if it didn't use up the player's turn. Inputs that don't, such
+// as saving or choosing an inventory item, don't give the monsters a move.
+func (game *Game) handleInput(input *Input) float64 {
 	level := game.CurrentLevel
 	p := level.Player
+	if p.Dead() {
+		return game.handleDeadInput(input)
+	}
 	switch input.Typ {
-	case Up:
-		newPos := Pos{p.X, p.Y - 1}
-		game.resolveMovement(newPos)
-	case Down:
-		newPos := Pos{p.X, p.Y + 1}
-		game.resolveMovement(newPos)
-	case Left:
-		newPos := Pos{p.X - 1, p.Y}
-		game.resolveMovement(newPos)
-	case Right:
-		newPos := Pos{p.X + 1, p.Y}
-		game.resolveMovement(newPos)
-	case CloseWindow:
-		close(input.LevelChannel)
-		chanIndex := 0
-		for i, c := range game.LevelChans {
-			if c == input.LevelChannel {
-				chanIndex = i
-				break
-			}
+	case Up, Down, Left, Right, UpLeft, UpRight, DownLeft, DownRight:
+		dir := directions[input.Typ]
+		newPos := Pos{p.X + dir.X, p.Y + dir.Y}
+		if diagonalBlocked(level, p.Pos, newPos) {
+			return 0
 		}
-		game.LevelChans = append(game.LevelChans[:chanIndex], game.LevelChans[chanIndex+1:]...)
+		return game.resolveMovement(newPos)
+	case CloseWindow:
+		game.closeWindow(input.LevelChannel)
+		return 0
+	case Explore:
+		return game.startTravel(&travel{explore: true})
+	case TravelTo:
+		return game.startTravel(&travel{target: input.Target})
+	case TravelUpStair:
+		return game.travelToStair(UpStair)
+	case TravelDownStair:
+		return game.travelToStair(DownStair)
+	case Attack:
+		return game.attackAt(input.Target)
+	case Inspect:
+		level.inspect(input.Target)
+		return 0
+	case PickUp:
+		level.pickUp()
+	case Drop:
+		level.drop()
+	case Use:
+		level.use()
+	case Equip:
+		level.equip()
+	case Unequip:
+		level.unequip()
+	case SelectNext:
+		p.selectItem(1)
+		return 0
+	case SelectPrev:
+		p.selectItem(-1)
+		return 0
+	case SaveGame:
+		game.saveToFile()
+		return 0
+	case LoadGame:
+		game.loadFromFile()
+		return 0
+	default:
+		return 0
 	}
+	return actionCost
 }
 
-func getNeighbors(level *Level, pos Pos) []Pos {
-	neighbors := make([]Pos, 0, 4)
-	left := Pos{pos.X - 1, pos.Y}
-	right := Pos{pos.X + 1, pos.Y}
-	up := Pos{pos.X, pos.Y - 1}
-	down := Pos{pos.X, pos.Y + 1}
-	if canWalk(level, left) {
-		neighbors = append(neighbors, left)
+func (game *Game) closeWindow(levelChan chan *Level) {
+	close(levelChan)
+	chanIndex := 0
+	for i, c := range game.LevelChans {
+		if c == levelChan {
+			chanIndex = i
+			break
+		}
 	}
-	if canWalk(level, right) {
-		neighbors = append(neighbors, right)
+	game.LevelChans = append(game.LevelChans[:chanIndex], game.LevelChans[chanIndex+1:]...)
+}
+
+// getNeighbors returns the walkable tiles next to pos, in a fixed order.
+// With diagonal set it also returns the diagonal neighbours that can be
+// reached without cutting a corner.
+func getNeighbors(level *Level, pos Pos, diagonal bool) []Pos {
+	neighbors := make([]Pos, 0, 8)
+	for _, dir := range orthogonals {
+		next := Pos{pos.X + dir.X, pos.Y + dir.Y}
+		if canWalk(level, next) {
+			neighbors = append(neighbors, next)
+		}
 	}
-	if canWalk(level, up) {
-		neighbors = append(neighbors, up)
+	if !diagonal {
+		return neighbors
 	}
-	if canWalk(level, down) {
-		neighbors = append(neighbors, down)
+	for _, dir := range diagonals {
+		next := Pos{pos.X + dir.X, pos.Y + dir.Y}
+		if canWalk(level, next) && !diagonalBlocked(level, pos, next) {
+			neighbors = append(neighbors, next)
+		}
 	}
 	return neighbors
 }
@@ -431,7 +834,7 @@ func (level *Level) bfsFloor(start Pos) rune {
 		default:
 		}
 		frontier = frontier[1:]
-		for _, next := range getNeighbors(level, current) {
+		for _, next := range getNeighbors(level, current, false) {
 			if !visited[next] {
 				frontier = append(frontier, next)
 				visited[next] = true
@@ -440,6 +843,9 @@ func (level *Level) bfsFloor(start Pos) rune {
 	}
 	return DirtFloor
 }
+
+// astar finds the cheapest 8-way path from start to goal, both included,
+// or nil if there is none.
 func (level *Level) astar(start Pos, goal Pos) []Pos {
 	frontier := make(pqueue, 0, 8)
 	frontier = frontier.push(start, 1)
@@ -468,14 +874,12 @@ func (level *Level) astar(start Pos, goal Pos) []Pos {
 
 			return path
 		}
-		for _, next := range getNeighbors(level, current) {
-			newCost := costSoFar[current] + 1
+		for _, next := range getNeighbors(level, current, true) {
+			newCost := costSoFar[current] + moveCost(level, current, next)
 			_, exists := costSoFar[next]
 			if !exists || newCost < costSoFar[next] {
 				costSoFar[next] = newCost
-				xDist := int(math.Abs(float64(goal.X - next.X)))
-				yDist := int(math.Abs(float64(goal.Y - next.Y)))
-				priority := newCost + xDist + yDist
+				priority := newCost + octile(next, goal)
 				frontier = frontier.push(next, priority)
 				cameFrom[next] = current
 			}
@@ -484,22 +888,24 @@ func (level *Level) astar(start Pos, goal Pos) []Pos {
 
 	return nil
 }
+
+// lineOfSight recomputes which tiles the player can see with the game's
+// field of view algorithm.
 func (level *Level) lineOfSight() {
-	pos := level.Player.Pos
-	dist := level.Player.SightRange
-
-	for y := pos.Y - dist; y <= pos.Y+dist; y++ {
-		for x := pos.X - dist; x <= pos.X+dist; x++ {
-			xDelta := pos.X - x
-			yDelta := pos.Y - y
-			d := math.Sqrt(float64(xDelta*xDelta + yDelta*yDelta))
-			if d <= float64(dist) {
-				level.bresenham(pos, Pos{x, y})
-			}
+	for y, row := range level.Map {
+		for x := range row {
+			level.Map[y][x].Visible = false
 		}
 	}
+	level.game.FOV.Compute(level, level.Player.Pos, level.Player.SightRange, func(pos Pos) {
+		level.Map[pos.Y][pos.X].Visible = true
+		level.Map[pos.Y][pos.X].Seen = true
+	})
 }
-func (level *Level) bresenham(start Pos, end Pos) {
+
+// castRay walks the Bresenham line from start towards end, stopping short
+// of end, and calls visit for every tile on it until visit returns false.
+func castRay(start Pos, end Pos, visit func(Pos) bool) {
 	steep := math.Abs(float64(end.Y-start.Y)) > math.Abs(float64(end.X-start.X))
 	if steep {
 		start.X, start.Y = start.Y, start.X
@@ -523,9 +929,7 @@ func (level *Level) bresenham(start Pos, end Pos) {
 			} else {
 				pos = Pos{x, y}
 			}
-			level.Map[pos.Y][pos.X].Visible = true
-			level.Map[pos.Y][pos.X].Seen = true
-			if !canSeeThrough(level, pos) {
+			if !visit(pos) {
 				return
 			}
 			err += deltaY
@@ -543,9 +947,7 @@ func (level *Level) bresenham(start Pos, end Pos) {
 			} else {
 				pos = Pos{x, y}
 			}
-			level.Map[pos.Y][pos.X].Visible = true
-			level.Map[pos.Y][pos.X].Seen = true
-			if !canSeeThrough(level, pos) {
+			if !visit(pos) {
 				return
 			}
 			err += deltaY
@@ -557,27 +959,109 @@ func (level *Level) bresenham(start Pos, end Pos) {
 	}
 }
 
+// Step applies a single input and, if it used up the player's turn, lets
+// the scheduler run the monsters on the current level until the player can
+// act again. It returns the level the player is now on. It is what Run does
+// for every input read from InputChan, and can be called directly to drive a
+// game without any frontend. An Explore or TravelTo input only takes the
+// first step; call StepTravel for the rest while Traveling is true.
+func (game *Game) Step(input *Input) *Level {
+	return game.takeTurn(func() float64 {
+		return game.handleInput(input)
+	})
+}
+
+// takeTurn carries out the player's action and, if it took a turn, charges
+// the player the energy it returns and runs the world until the player is
+// ready again.
+func (game *Game) takeTurn(act func() float64) *Level {
+	level := game.CurrentLevel
+	level.Noise = 0
+	events := level.eventCount
+	visible := level.visibleMonsters()
+	cost := act()
+	if cost == 0 {
+		return game.CurrentLevel
+	}
+	player := game.CurrentLevel.Player
+	player.ActionPoints -= cost
+	player.Turns++
+	game.advance()
+	if game.travel != nil && game.interrupted(level, events, visible) {
+		game.stopTravel()
+	}
+	return game.CurrentLevel
+}
+
+// Run reads inputs from InputChan and sends a snapshot of the level to
+// every window after each one until the game is quit or the last window is closed. While the
+// player is travelling it keeps taking steps on its own, and any input
+// stops the travel.
 func (game *Game) Run() {
-	for _, lchan := range game.LevelChans {
-		lchan <- game.CurrentLevel
+	pending, quit := game.publish(nil)
+	if quit {
+		return
 	}
 
-	for input := range game.InputChan {
-		if input.Typ == QuitGame {
-			return
+	for {
+		var input *Input
+		if len(pending) > 0 {
+			input = pending[0]
+			pending = pending[1:]
+		} else if game.Traveling() {
+			select {
+			case input = <-game.InputChan:
+				game.stopTravel()
+			case <-time.After(travelDelay):
+			}
+		} else {
+			var ok bool
+			input, ok = <-game.InputChan
+			if !ok {
+				return
+			}
 		}
-		game.handleInput(input)
-		for _, monster := range game.CurrentLevel.Monsters {
-			monster.Update(game.CurrentLevel)
+
+		if input == nil {
+			game.StepTravel()
+		} else {
+			if input.Typ == QuitGame {
+				return
+			}
+			game.Step(input)
 		}
 
 		if len(game.LevelChans) == 0 {
 			return
 		}
-
-		for _, lchan := range game.LevelChans {
-			lchan <- game.CurrentLevel
+		pending, quit = game.publish(pending)
+		if quit {
+			return
 		}
+	}
+}
 
+// publish sends a snapshot of the current level to every window, so that
+// windows can draw it whenever they like while the game changes the level
+// itself. A window may send an input before it has taken the level, so inputs are queued up meanwhile
+// rather than blocking both sides; any input stops travel. A window that
+// quits may never take the level, so quitting stops publishing at once and
+// publish reports that the game should end.
+func (game *Game) publish(pending []*Input) ([]*Input, bool) {
+	level := game.CurrentLevel.snapshot()
+	for _, lchan := range game.LevelChans {
+		for sent := false; !sent; {
+			select {
+			case lchan <- level:
+				sent = true
+			case input, ok := <-game.InputChan:
+				if !ok || input.Typ == QuitGame {
+					return pending, true
+				}
+				game.stopTravel()
+				pending = append(pending, input)
+			}
+		}
 	}
+	return pending, false
 }
@@ -0,0 +1,60 @@
+package game
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+// The map and data files are found relative to the repository root, as
+// they are for the commands.
+func TestMain(m *testing.M) {
+	err := os.Chdir("..")
+	if err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+func TestStepScript(t *testing.T) {
+	g, err := NewGame(0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	script := []InputType{Right, Right, Down, Left, UpLeft, Search, Explore}
+	for _, typ := range script {
+		g.Step(&Input{Typ: typ})
+	}
+	for steps := 0; g.Traveling(); steps++ {
+		if steps > 1000 {
+			t.Fatal("exploring never stopped")
+		}
+		g.StepTravel()
+	}
+	if g.CurrentLevel.Player.Turns == 0 {
+		t.Error("no input took a turn")
+	}
+	g.Step(&Input{Typ: QuitGame})
+}
+
+// A window that quits without taking the level it was last sent must not
+// leave Run waiting for it.
+func TestRunQuits(t *testing.T) {
+	g, err := NewGame(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan bool)
+	go func() {
+		g.Run()
+		close(done)
+	}()
+	<-g.LevelChans[0]
+	g.InputChan <- &Input{Typ: Left}
+	g.InputChan <- &Input{Typ: QuitGame}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after QuitGame")
+	}
+}
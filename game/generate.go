@@ -0,0 +1,274 @@
+package game
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+)
+
+// minLeafSize is the smallest area the generator will carve a room into.
+const minLeafSize = 10
+
+type room struct {
+	x, y, w, h int
+}
+
+func (r room) center() Pos {
+	return Pos{r.x + r.w/2, r.y + r.h/2}
+}
+
+type generator struct {
+	rng      *rand.Rand
+	level    *Level
+	rooms    []room
+	monsters []*MonsterKind
+	items    []*ItemKind
+}
+
+// GenerateLevel builds a dungeon of rooms joined by corridors by splitting
+// the map in two over and over (binary space partitioning) and putting a
+// room in every piece. Doorways get closed doors, the first room gets an up
+// stair and the last a down stair, and the rooms are stocked with monsters
+// and items picked from the given kinds. The same seed always gives the same
+// level. The returned level has no player; the caller attaches one.
+func GenerateLevel(name string, width, height int, seed int64, monsters []*MonsterKind, items []*ItemKind) (*Level, error) {
+	if width < minLeafSize || height < minLeafSize {
+		return nil, fmt.Errorf("generated level must be at least %dx%d", minLeafSize, minLeafSize)
+	}
+	g := &generator{}
+	g.rng = rand.New(rand.NewSource(seed))
+	g.level = newLevel(name, width, height, nil)
+	g.monsters = monsters
+	g.items = items
+
+	g.bsp(0, 0, width, height)
+	g.buildWalls()
+	g.placeDoors()
+
+	first := g.rooms[0].center()
+	last := g.rooms[len(g.rooms)-1].center()
+	g.level.Map[first.Y][first.X].OverlayRune = UpStair
+	if last != first {
+		g.level.Map[last.Y][last.X].OverlayRune = DownStair
+	}
+
+	g.stock()
+	return g.level, nil
+}
+
+// bsp carves rooms into the area, splitting it first if it is big enough,
+// and joins the two halves of every split with a corridor. It returns the
+// rooms carved inside the area.
+func (g *generator) bsp(x, y, w, h int) []room {
+	canSplitX := w >= 2*minLeafSize
+	canSplitY := h >= 2*minLeafSize
+	if !canSplitX && !canSplitY {
+		r := g.carveRoom(x, y, w, h)
+		g.rooms = append(g.rooms, r)
+		return []room{r}
+	}
+
+	splitX := canSplitX && (!canSplitY || w > h || w == h && g.rng.Intn(2) == 0)
+	var a, b []room
+	if splitX {
+		cut := minLeafSize + g.rng.Intn(w-2*minLeafSize+1)
+		a = g.bsp(x, y, cut, h)
+		b = g.bsp(x+cut, y, w-cut, h)
+	} else {
+		cut := minLeafSize + g.rng.Intn(h-2*minLeafSize+1)
+		a = g.bsp(x, y, w, cut)
+		b = g.bsp(x, y+cut, w, h-cut)
+	}
+	g.carveCorridor(a[g.rng.Intn(len(a))].center(), b[g.rng.Intn(len(b))].center())
+	return append(a, b...)
+}
+
+// carveRoom digs a room somewhere inside the area, leaving at least one
+// tile on each side for walls.
+func (g *generator) carveRoom(x, y, w, h int) room {
+	r := room{}
+	r.w = 4 + g.rng.Intn(w-2-4+1)
+	r.h = 4 + g.rng.Intn(h-2-4+1)
+	r.x = x + 1 + g.rng.Intn(w-2-r.w+1)
+	r.y = y + 1 + g.rng.Intn(h-2-r.h+1)
+	for ty := r.y; ty < r.y+r.h; ty++ {
+		for tx := r.x; tx < r.x+r.w; tx++ {
+			g.level.Map[ty][tx].Rune = DirtFloor
+		}
+	}
+	return r
+}
+
+func (g *generator) carveCorridor(from, to Pos) {
+	horizontal := func(x1, x2, y int) {
+		if x1 > x2 {
+			x1, x2 = x2, x1
+		}
+		for x := x1; x <= x2; x++ {
+			g.level.Map[y][x].Rune = DirtFloor
+		}
+	}
+	vertical := func(y1, y2, x int) {
+		if y1 > y2 {
+			y1, y2 = y2, y1
+		}
+		for y := y1; y <= y2; y++ {
+			g.level.Map[y][x].Rune = DirtFloor
+		}
+	}
+	if g.rng.Intn(2) == 0 {
+		horizontal(from.X, to.X, from.Y)
+		vertical(from.Y, to.Y, to.X)
+	} else {
+		vertical(from.Y, to.Y, from.X)
+		horizontal(from.X, to.X, to.Y)
+	}
+}
+
+// buildWalls puts stone walls around every floor tile, leaving everything
+// further out blank like the hand drawn maps.
+func (g *generator) buildWalls() {
+	level := g.level
+	for y, row := range level.Map {
+		for x, tile := range row {
+			if tile.Rune != Blank {
+				continue
+			}
+		neighbours:
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					pos := Pos{x + dx, y + dy}
+					if inRange(level, pos) && level.Map[pos.Y][pos.X].Rune == DirtFloor {
+						level.Map[y][x].Rune = StoneWall
+						break neighbours
+					}
+				}
+			}
+		}
+	}
+}
+
+// placeDoors looks at the ring of tiles just outside every room and puts a
+// door in some of the gaps where a corridor passes through the wall.
+func (g *generator) placeDoors() {
+	level := g.level
+	isWall := func(x, y int) bool {
+		return level.Map[y][x].Rune == StoneWall
+	}
+	tryDoor := func(x, y int, acrossX bool) {
+		tile := &level.Map[y][x]
+		if tile.Rune != DirtFloor || tile.OverlayRune != Blank {
+			return
+		}
+		if acrossX && !(isWall(x-1, y) && isWall(x+1, y)) {
+			return
+		}
+		if !acrossX && !(isWall(x, y-1) && isWall(x, y+1)) {
+			return
+		}
+		if g.rng.Intn(2) == 0 {
+			tile.OverlayRune = CloseDoor
+		}
+	}
+	for _, r := range g.rooms {
+		for x := r.x; x < r.x+r.w; x++ {
+			tryDoor(x, r.y-1, true)
+			tryDoor(x, r.y+r.h, true)
+		}
+		for y := r.y; y < r.y+r.h; y++ {
+			tryDoor(r.x-1, y, false)
+			tryDoor(r.x+r.w, y, false)
+		}
+	}
+}
+
+// stock places monsters in most rooms after the first and an item in some.
+func (g *generator) stock() {
+	level := g.level
+	for _, r := range g.rooms[1:] {
+		if len(g.monsters) > 0 && g.rng.Intn(10) < 6 {
+			pos, ok := g.freeSpot(r)
+			if ok {
+				kind := g.monsters[g.rng.Intn(len(g.monsters))]
+				level.Monsters[pos] = NewMonster(kind, pos)
+			}
+		}
+		if len(g.items) > 0 && g.rng.Intn(10) < 3 {
+			pos, ok := g.freeSpot(r)
+			if ok {
+				kind := g.items[g.rng.Intn(len(g.items))]
+				level.Items[pos] = append(level.Items[pos], NewItem(kind, pos))
+			}
+		}
+	}
+}
+
+func (g *generator) freeSpot(r room) (Pos, bool) {
+	for tries := 0; tries < 20; tries++ {
+		pos := Pos{r.x + g.rng.Intn(r.w), r.y + g.rng.Intn(r.h)}
+		tile := g.level.Map[pos.Y][pos.X]
+		_, monster := g.level.Monsters[pos]
+		if tile.OverlayRune == Blank && !monster && len(g.level.Items[pos]) == 0 {
+			return pos, true
+		}
+	}
+	return Pos{}, false
+}
+
+// generateFromRow handles a "generate, name, width, height, seed" row of
+// world.txt.
+func (game *Game) generateFromRow(row []string) error {
+	if len(row) != 5 {
+		return fmt.Errorf("generate needs a name, width, height and seed")
+	}
+	name := row[1]
+	if game.Levels[name] != nil {
+		return fmt.Errorf("level %q already exists", name)
+	}
+	width, err := strconv.Atoi(row[2])
+	if err != nil {
+		return err
+	}
+	height, err := strconv.Atoi(row[3])
+	if err != nil {
+		return err
+	}
+	seed, err := strconv.ParseInt(row[4], 10, 64)
+	if err != nil {
+		return err
+	}
+
+	level, err := GenerateLevel(name, width, height, seed, game.catalogue.monsterKinds(), game.catalogue.itemKinds())
+	if err != nil {
+		return err
+	}
+	level.Player = game.CurrentLevel.Player
+	level.game = game
+	game.Levels[name] = level
+	return nil
+}
+
+// monsterKinds lists the catalogued monsters in rune order, so generated
+// levels don't depend on map iteration order.
+func (cat *catalogue) monsterKinds() []*MonsterKind {
+	kinds := make([]*MonsterKind, 0, len(cat.monsters))
+	for _, kind := range cat.monsters {
+		kinds = append(kinds, kind)
+	}
+	sort.Slice(kinds, func(i, j int) bool {
+		return kinds[i].Rune < kinds[j].Rune
+	})
+	return kinds
+}
+
+func (cat *catalogue) itemKinds() []*ItemKind {
+	kinds := make([]*ItemKind, 0, len(cat.items))
+	for _, kind := range cat.items {
+		kinds = append(kinds, kind)
+	}
+	sort.Slice(kinds, func(i, j int) bool {
+		return kinds[i].Rune < kinds[j].Rune
+	})
+	return kinds
+}
@@ -0,0 +1,82 @@
+package game
+
+import "fmt"
+
+// tileNames are what Describe calls each kind of tile.
+var tileNames = map[rune]string{
+	StoneWall: "stone wall",
+	DirtFloor: "floor",
+	CloseDoor: "closed door",
+	OpenDoor:  "open door",
+	UpStair:   "stairs up",
+	DownStair: "stairs down",
+	Water:     "water",
+	Rubble:    "rubble",
+	Mud:       "deep mud",
+}
+
+func (s AIState) String() string {
+	switch s {
+	case Resting:
+		return "resting"
+	case Wandering:
+		return "wandering"
+	case Hunting:
+		return "hunting"
+	case Fleeing:
+		return "fleeing"
+	}
+	return "unknown"
+}
+
+// Describe names what the player knows to be at pos: themselves, a monster
+// they can see, the top item of a pile, or else the tile itself. It returns
+// "" for tiles the player has never seen.
+func (level *Level) Describe(pos Pos) string {
+	if !inRange(level, pos) {
+		return ""
+	}
+	tile := level.Map[pos.Y][pos.X]
+	if !tile.Seen {
+		return ""
+	}
+	if pos == level.Player.Pos {
+		return level.Player.Name
+	}
+	if monster, exists := level.Monsters[pos]; exists && tile.Visible {
+		return monster.Name
+	}
+	if items := level.Items[pos]; len(items) > 0 {
+		return items[len(items)-1].Name
+	}
+	if tile.OverlayRune != Blank {
+		return tileNames[tile.OverlayRune]
+	}
+	return tileNames[tile.Rune]
+}
+
+// inspect adds an event describing pos in more detail than Describe.
+func (level *Level) inspect(pos Pos) {
+	name := level.Describe(pos)
+	if name == "" {
+		level.AddEvent(System, "You haven't seen that")
+		return
+	}
+	monster, exists := level.Monsters[pos]
+	if exists && level.Map[pos.Y][pos.X].Visible {
+		level.AddEvent(Discovery, fmt.Sprintf("You see %s (%d/%d hp, %s)", name, monster.Hitpoints, monster.MaxHitpoints, monster.State))
+		return
+	}
+	level.AddEvent(Discovery, "You see "+name)
+}
+
+// attackAt attacks the monster at pos, which must be next to the player.
+func (game *Game) attackAt(pos Pos) float64 {
+	level := game.CurrentLevel
+	_, exists := level.Monsters[pos]
+	if !exists || !level.adjacent(level.Player.Pos, pos) {
+		level.AddEvent(System, "There is nothing there to attack")
+		return 0
+	}
+	return game.resolveMovement(pos)
+}
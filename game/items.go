@@ -0,0 +1,263 @@
+package game
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const itemFile = "game/data/items.csv"
+
+type ItemType int
+
+const (
+	Potion ItemType = iota
+	Weapon
+	Armour
+	Ring
+	Key
+	HastePotion
+	SlowPotion
+)
+
+var itemTypeNames = map[string]ItemType{
+	"potion": Potion,
+	"weapon": Weapon,
+	"armour": Armour,
+	"ring":   Ring,
+	"key":    Key,
+	"haste":  HastePotion,
+	"slow":   SlowPotion,
+}
+
+// ItemKind is one entry of the item catalogue. Power means hitpoints healed
+// for potions and how many ticks haste and slow potions last, and is unused
+// by other item types. Items with a Slot can be equipped and add their
+// Attack, Defence and Accuracy bonuses to whoever wears them; a weapon's
+// Damage replaces its wielder's natural damage dice.
+type ItemKind struct {
+	Rune     rune
+	Name     string
+	Typ      ItemType
+	Power    int
+	Slot     Slot
+	Attack   int
+	Defence  int
+	Accuracy int
+	Damage   Dice
+}
+
+type Item struct {
+	Entity
+	Typ      ItemType
+	Power    int
+	Slot     Slot
+	Attack   int
+	Defence  int
+	Accuracy int
+	Damage   Dice
+	Equipped bool
+}
+
+func NewItem(kind *ItemKind, p Pos) *Item {
+	item := &Item{}
+	item.Pos = p
+	item.Rune = kind.Rune
+	item.Name = kind.Name
+	item.Typ = kind.Typ
+	item.Power = kind.Power
+	item.Slot = kind.Slot
+	item.Attack = kind.Attack
+	item.Defence = kind.Defence
+	item.Accuracy = kind.Accuracy
+	item.Damage = kind.Damage
+	return item
+}
+
+// loadItemKinds reads the item catalogue, keyed by map rune.
+func loadItemKinds(filename string) (map[rune]*ItemKind, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	csvReader := csv.NewReader(file)
+	csvReader.Comment = '#'
+	csvReader.FieldsPerRecord = 9
+	csvReader.TrimLeadingSpace = true
+
+	kinds := make(map[rune]*ItemKind)
+	for {
+		row, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := csvReader.FieldPos(0)
+		kind, err := parseItemKind(row)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", filename, line, err)
+		}
+		if _, exists := kinds[kind.Rune]; exists {
+			return nil, fmt.Errorf("%s:%d: duplicate item rune %q", filename, line, kind.Rune)
+		}
+		kinds[kind.Rune] = kind
+	}
+	return kinds, nil
+}
+
+func parseItemKind(row []string) (*ItemKind, error) {
+	kind := &ItemKind{}
+	r, size := utf8.DecodeRuneInString(row[0])
+	if size == 0 || size != len(row[0]) {
+		return nil, fmt.Errorf("item rune %q must be a single character", row[0])
+	}
+	if err := checkCatalogueRune(r); err != nil {
+		return nil, err
+	}
+	kind.Rune = r
+	kind.Name = row[1]
+
+	typ, exists := itemTypeNames[strings.ToLower(row[2])]
+	if !exists {
+		return nil, fmt.Errorf("unknown item type %q", row[2])
+	}
+	kind.Typ = typ
+
+	var err error
+	kind.Power, err = strconv.Atoi(row[3])
+	if err != nil {
+		return nil, fmt.Errorf("bad power: %v", err)
+	}
+	slot, exists := slotNames[strings.ToLower(row[4])]
+	if !exists {
+		return nil, fmt.Errorf("unknown slot %q", row[4])
+	}
+	kind.Slot = slot
+	kind.Attack, err = strconv.Atoi(row[5])
+	if err != nil {
+		return nil, fmt.Errorf("bad attack: %v", err)
+	}
+	kind.Defence, err = strconv.Atoi(row[6])
+	if err != nil {
+		return nil, fmt.Errorf("bad defence: %v", err)
+	}
+	kind.Accuracy, err = strconv.Atoi(row[7])
+	if err != nil {
+		return nil, fmt.Errorf("bad accuracy: %v", err)
+	}
+	if row[8] != "-" {
+		kind.Damage, err = ParseDice(row[8])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return kind, nil
+}
+
+// SelectedItem returns the inventory item that drop and use act on, or nil
+// if the inventory is empty.
+func (p *Player) SelectedItem() *Item {
+	if p.Selected < 0 || p.Selected >= len(p.Inventory) {
+		return nil
+	}
+	return p.Inventory[p.Selected]
+}
+
+func (p *Player) selectItem(delta int) {
+	if len(p.Inventory) == 0 {
+		p.Selected = 0
+		return
+	}
+	p.Selected = (p.Selected + delta + len(p.Inventory)) % len(p.Inventory)
+}
+
+func (p *Player) removeItem(item *Item) {
+	for i, it := range p.Inventory {
+		if it == item {
+			p.Inventory = append(p.Inventory[:i], p.Inventory[i+1:]...)
+			break
+		}
+	}
+	if p.Selected >= len(p.Inventory) {
+		p.Selected = len(p.Inventory) - 1
+	}
+	if p.Selected < 0 {
+		p.Selected = 0
+	}
+}
+
+func (level *Level) pickUp() {
+	p := level.Player
+	items := level.Items[p.Pos]
+	if len(items) == 0 {
+		level.AddEvent(System, "There is nothing here to pick up")
+		return
+	}
+	item := items[len(items)-1]
+	if len(items) == 1 {
+		delete(level.Items, p.Pos)
+	} else {
+		level.Items[p.Pos] = items[:len(items)-1]
+	}
+	p.Inventory = append(p.Inventory, item)
+	level.AddEvent(Discovery, p.Name+" picked up "+item.Name)
+}
+
+func (level *Level) drop() {
+	p := level.Player
+	item := p.SelectedItem()
+	if item == nil {
+		level.AddEvent(System, "You have nothing to drop")
+		return
+	}
+	if item.Equipped {
+		level.takeOff(&p.Character, item)
+	}
+	p.removeItem(item)
+	item.Pos = p.Pos
+	level.Items[p.Pos] = append(level.Items[p.Pos], item)
+	level.AddEvent(System, p.Name+" dropped "+item.Name)
+}
+
+func (level *Level) use() {
+	p := level.Player
+	item := p.SelectedItem()
+	if item == nil {
+		level.AddEvent(System, "You have nothing to use")
+		return
+	}
+	switch item.Typ {
+	case Potion:
+		healed := item.Power
+		if p.Hitpoints+healed > p.MaxHitpoints {
+			healed = p.MaxHitpoints - p.Hitpoints
+		}
+		p.Hitpoints += healed
+		p.removeItem(item)
+		level.AddEvent(System, p.Name+" drank "+item.Name+" and healed "+strconv.Itoa(healed))
+	case HastePotion:
+		p.removeItem(item)
+		level.AddEvent(System, p.Name+" drank "+item.Name)
+		level.addEffect(&p.Character, &Effect{"hasted", 2, item.Power})
+	case SlowPotion:
+		p.removeItem(item)
+		level.AddEvent(System, p.Name+" drank "+item.Name)
+		level.addEffect(&p.Character, &Effect{"slowed", 0.5, item.Power})
+	default:
+		if item.Slot == NoSlot {
+			level.AddEvent(System, p.Name+" can't use "+item.Name)
+		} else if item.Equipped {
+			level.takeOff(&p.Character, item)
+		} else {
+			level.putOn(&p.Character, item)
+		}
+	}
+}
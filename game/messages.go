@@ -0,0 +1,74 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Category sorts messages so the log can be filtered.
+type Category int
+
+const (
+	System Category = iota
+	Combat
+	Discovery
+)
+
+var categoryNames = map[string]Category{
+	"system":    System,
+	"combat":    Combat,
+	"discovery": Discovery,
+}
+
+// ParseCategory looks up a message category by name: system, combat or
+// discovery.
+func ParseCategory(name string) (Category, error) {
+	category, ok := categoryNames[strings.ToLower(name)]
+	if !ok {
+		return System, fmt.Errorf("unknown message category %q", name)
+	}
+	return category, nil
+}
+
+func (c Category) String() string {
+	for name, category := range categoryNames {
+		if category == c {
+			return name
+		}
+	}
+	return fmt.Sprintf("category %d", int(c))
+}
+
+// Message is one entry of the game's message history. Turn is the number
+// of turns the player had taken when it happened, counted from the start of
+// the Adventure it belongs to.
+type Message struct {
+	Adventure int
+	Turn      int
+	Category  Category
+	Level     string
+	Text      string
+}
+
+// DefaultMaxMessages is how many messages a new game keeps before it starts
+// forgetting the oldest.
+const DefaultMaxMessages = 1000
+
+// addMessage records a message in the history, dropping the oldest once
+// there are more than MaxMessages. A MaxMessages of 0 or less keeps
+// everything.
+func (game *Game) addMessage(msg Message) {
+	game.Messages = append(game.Messages, msg)
+	if game.MaxMessages > 0 && len(game.Messages) > game.MaxMessages {
+		game.Messages = game.Messages[len(game.Messages)-game.MaxMessages:]
+	}
+}
+
+// History returns every message the game remembers, oldest first, or for a
+// level received from the game every message it remembered then.
+func (level *Level) History() []Message {
+	if level.game == nil {
+		return level.history
+	}
+	return level.game.Messages
+}
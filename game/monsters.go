@@ -1,75 +1,215 @@
 package game
 
-type Monster struct {
-	Character
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const monsterFile = "game/data/monsters.csv"
+
+// Behaviour is what a monster does while it isn't hunting or fleeing from
+// the player.
+type Behaviour int
+
+const (
+	// Idle monsters stay where they are until they notice the player.
+	Idle Behaviour = iota
+	// Wander monsters roam about at random until they notice the player.
+	Wander
+	// Stationary monsters never move but attack a player standing next to them.
+	Stationary
+)
+
+var behaviourNames = map[string]Behaviour{
+	"idle":       Idle,
+	"wander":     Wander,
+	"stationary": Stationary,
 }
 
-func NewRat(p Pos) *Monster {
-	monster := &Monster{}
-	monster.Pos = p
-	monster.Rune = 'R'
-	monster.Name = "Rat"
-	monster.Hitpoints = 50
-	monster.Strength = 5
-	monster.Speed = 1.5
-	monster.ActionPoints = 0.0
-	monster.SightRange = 10
+// MonsterKind is one entry of the monster catalogue. Every monster placed
+// on a map is created from the kind registered for its rune.
+type MonsterKind struct {
+	Rune       rune
+	Name       string
+	Hitpoints  int
+	Strength   int
+	Speed      float64
+	SightRange int
+	Accuracy   int
+	Evasion    int
+	Damage     Dice
+	Behaviour  Behaviour
+	// FleeAt is the percentage of its hitpoints below which the monster
+	// runs away; 0 means it fights to the death.
+	FleeAt int
+	// Memory is how many turns a hunting monster keeps looking for the
+	// player after losing sight of them.
+	Memory int
+}
 
-	return monster
+type Monster struct {
+	Character
+	Behaviour Behaviour
+	FleeAt    int
+	Memory    int
+	State     AIState
+	// LastKnown is where the monster last saw the player and Lost counts the
+	// turns since then.
+	LastKnown Pos
+	Lost      int
+	// sight is what the monster could see when it last acted, and
+	// heardNoise the last noise it reacted to.
+	sight      map[Pos]bool
+	heardNoise int
 }
 
-func NewSpider(p Pos) *Monster {
+func NewMonster(kind *MonsterKind, p Pos) *Monster {
 	monster := &Monster{}
 	monster.Pos = p
-	monster.Rune = 'S'
-	monster.Name = "Spider"
-	monster.Hitpoints = 100
-	monster.Strength = 5
-	monster.Speed = 1.0
+	monster.Rune = kind.Rune
+	monster.Name = kind.Name
+	monster.Hitpoints = kind.Hitpoints
+	monster.MaxHitpoints = kind.Hitpoints
+	monster.Strength = kind.Strength
+	monster.Speed = kind.Speed
 	monster.ActionPoints = 0.0
-	monster.SightRange = 10
+	monster.SightRange = kind.SightRange
+	monster.Accuracy = kind.Accuracy
+	monster.Evasion = kind.Evasion
+	monster.Damage = kind.Damage
+	monster.Behaviour = kind.Behaviour
+	monster.FleeAt = kind.FleeAt
+	monster.Memory = kind.Memory
+	monster.State = monster.calmState()
 	return monster
 }
-func (m *Monster) Pass() {
-	m.ActionPoints -= m.Speed
-}
 
-func (m *Monster) Update(level *Level) {
-	m.ActionPoints += m.Speed
-	playerPos := level.Player.Pos
+// loadMonsterKinds reads the monster catalogue, keyed by map rune.
+func loadMonsterKinds(filename string) (map[rune]*MonsterKind, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
 
-	apInt := int(m.ActionPoints)
-	positions := level.astar(m.Pos, playerPos)
-	moveIndex := 1
+	csvReader := csv.NewReader(file)
+	csvReader.Comment = '#'
+	csvReader.FieldsPerRecord = 12
+	csvReader.TrimLeadingSpace = true
 
-	if len(positions) == 0 {
-		m.Pass()
-		return
-	}
-	for i := 0; i < apInt; i++ {
-		if moveIndex < len(positions) {
-			m.Move(positions[moveIndex], level)
-			moveIndex++
-			m.ActionPoints--
+	kinds := make(map[rune]*MonsterKind)
+	for {
+		row, err := csvReader.Read()
+		if err == io.EOF {
+			break
 		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := csvReader.FieldPos(0)
+		kind, err := parseMonsterKind(row)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", filename, line, err)
+		}
+		if _, exists := kinds[kind.Rune]; exists {
+			return nil, fmt.Errorf("%s:%d: duplicate monster rune %q", filename, line, kind.Rune)
+		}
+		kinds[kind.Rune] = kind
 	}
+	return kinds, nil
 }
 
-func (m *Monster) Move(to Pos, level *Level) {
+func parseMonsterKind(row []string) (*MonsterKind, error) {
+	kind := &MonsterKind{}
+	r, size := utf8.DecodeRuneInString(row[0])
+	if size == 0 || size != len(row[0]) {
+		return nil, fmt.Errorf("monster rune %q must be a single character", row[0])
+	}
+	if err := checkCatalogueRune(r); err != nil {
+		return nil, err
+	}
+	kind.Rune = r
+	kind.Name = row[1]
+
+	var err error
+	kind.Hitpoints, err = strconv.Atoi(row[2])
+	if err != nil {
+		return nil, fmt.Errorf("bad hitpoints: %v", err)
+	}
+	kind.Strength, err = strconv.Atoi(row[3])
+	if err != nil {
+		return nil, fmt.Errorf("bad strength: %v", err)
+	}
+	kind.Speed, err = strconv.ParseFloat(row[4], 64)
+	if err != nil {
+		return nil, fmt.Errorf("bad speed: %v", err)
+	}
+	kind.SightRange, err = strconv.Atoi(row[5])
+	if err != nil {
+		return nil, fmt.Errorf("bad sight range: %v", err)
+	}
+	kind.Accuracy, err = strconv.Atoi(row[6])
+	if err != nil {
+		return nil, fmt.Errorf("bad accuracy: %v", err)
+	}
+	kind.Evasion, err = strconv.Atoi(row[7])
+	if err != nil {
+		return nil, fmt.Errorf("bad evasion: %v", err)
+	}
+	kind.Damage, err = ParseDice(row[8])
+	if err != nil {
+		return nil, err
+	}
+	behaviour, exists := behaviourNames[strings.ToLower(row[9])]
+	if !exists {
+		return nil, fmt.Errorf("unknown behaviour %q", row[9])
+	}
+	kind.Behaviour = behaviour
+	kind.FleeAt, err = strconv.Atoi(row[10])
+	if err != nil {
+		return nil, fmt.Errorf("bad flee threshold: %v", err)
+	}
+	kind.Memory, err = strconv.Atoi(row[11])
+	if err != nil {
+		return nil, fmt.Errorf("bad memory: %v", err)
+	}
+	return kind, nil
+}
+
+// Move walks the monster to to, or attacks the player if they are there,
+// and returns the energy it cost.
+func (m *Monster) Move(to Pos, level *Level) float64 {
 	_, exists := level.Monsters[to]
 	if !exists && to != level.Player.Pos {
 		delete(level.Monsters, m.Pos)
 		level.Monsters[to] = m
 		m.Pos = to
-		return
+		return stepEnergy(level, to)
 	}
 	if to == level.Player.Pos {
 		level.Attack(&m.Character, &level.Player.Character)
 		if m.Hitpoints <= 0 {
 			delete(level.Monsters, m.Pos)
 		}
-		if level.Player.Hitpoints <= 0 {
-			level.AddEvent("You have died")
-		}
 	}
+	return actionCost
+}
+
+// sortedMonsters returns the level's monsters ordered by position so that
+// they always take their turns in the same order.
+func (level *Level) sortedMonsters() []*Monster {
+	monsters := make([]*Monster, 0, len(level.Monsters))
+	for _, monster := range level.Monsters {
+		monsters = append(monsters, monster)
+	}
+	sort.Slice(monsters, func(i, j int) bool {
+		return posLess(monsters[i].Pos, monsters[j].Pos)
+	})
+	return monsters
 }
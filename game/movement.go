@@ -0,0 +1,102 @@
+package game
+
+// Path costs are scaled so that a diagonal step, which covers about 1.4
+// times the distance of an orthogonal one, can be costed in whole numbers.
+const (
+	stepCost     = 10
+	diagonalCost = 14
+)
+
+// directions gives the offset each movement input moves the player by.
+var directions = map[InputType]Pos{
+	Up:        {0, -1},
+	Down:      {0, 1},
+	Left:      {-1, 0},
+	Right:     {1, 0},
+	UpLeft:    {-1, -1},
+	UpRight:   {1, -1},
+	DownLeft:  {-1, 1},
+	DownRight: {1, 1},
+}
+
+// orthogonals and diagonals are the offsets to a tile's neighbours, and
+// compass is all eight of them.
+var (
+	orthogonals = []Pos{{-1, 0}, {1, 0}, {0, -1}, {0, 1}}
+	diagonals   = []Pos{{-1, -1}, {1, -1}, {-1, 1}, {1, 1}}
+	compass     = append(append([]Pos{}, orthogonals...), diagonals...)
+)
+
+func isDiagonal(from, to Pos) bool {
+	return from.X != to.X && from.Y != to.Y
+}
+
+func isDoor(level *Level, pos Pos) bool {
+	if !inRange(level, pos) {
+		return false
+	}
+	switch level.Map[pos.Y][pos.X].OverlayRune {
+	case CloseDoor, OpenDoor:
+		return true
+	}
+	return false
+}
+
+func isSolid(level *Level, pos Pos) bool {
+	if !inRange(level, pos) {
+		return true
+	}
+	t := level.Map[pos.Y][pos.X]
+	return t.Rune == StoneWall || t.Rune == Blank || t.OverlayRune == CloseDoor
+}
+
+// diagonalBlocked reports whether a diagonal step between two neighbouring
+// tiles is not allowed. Nobody squeezes past the corner of a wall or a
+// closed door, or steps diagonally into or out of a doorway. Orthogonal
+// steps are never blocked by these rules.
+func diagonalBlocked(level *Level, from, to Pos) bool {
+	if !isDiagonal(from, to) {
+		return false
+	}
+	if isDoor(level, from) || isDoor(level, to) {
+		return true
+	}
+	return isSolid(level, Pos{to.X, from.Y}) || isSolid(level, Pos{from.X, to.Y})
+}
+
+// adjacent reports whether a and b are next to each other, diagonals
+// included, with no corner in the way, so that one can attack the other.
+func (level *Level) adjacent(a, b Pos) bool {
+	dx, dy := a.X-b.X, a.Y-b.Y
+	if a == b || dx < -1 || dx > 1 || dy < -1 || dy > 1 {
+		return false
+	}
+	return !diagonalBlocked(level, a, b)
+}
+
+// moveCost is the path cost of a single step between neighbouring tiles,
+// taking the terrain being stepped onto into account.
+func moveCost(level *Level, from, to Pos) int {
+	cost := stepCost
+	if isDiagonal(from, to) {
+		cost = diagonalCost
+	}
+	return cost * terrainCost(level, to) / normalTerrain
+}
+
+// octile is the cost of the shortest path between two positions on an open
+// floor where diagonal steps are allowed. Slow terrain only ever adds to
+// that, so it never overestimates and astar still finds the cheapest path.
+func octile(a, b Pos) int {
+	dx, dy := a.X-b.X, a.Y-b.Y
+	if dx < 0 {
+		dx = -dx
+	}
+	if dy < 0 {
+		dy = -dy
+	}
+	if dx < dy {
+		dx, dy = dy, dx
+	}
+	return stepCost*(dx-dy) + diagonalCost*dy
+}
@@ -0,0 +1,35 @@
+package game
+
+// How far, in tiles, the noise of each of the player's actions carries
+// before the player's Stealth is taken off.
+const (
+	noiseMove   = 5
+	noiseDoor   = 10
+	noiseAttack = 12
+)
+
+// makeNoise records a sound the player made at pos this turn. Only the
+// loudest sound of the turn is kept, and a stealthy enough player makes
+// none at all.
+func (level *Level) makeNoise(pos Pos, loudness int) {
+	loudness -= level.Player.Stealth
+	if loudness <= 0 || loudness <= level.Noise {
+		return
+	}
+	level.Noise = loudness
+	level.NoisePos = pos
+	level.noiseID++
+}
+
+// hears reports whether the monster is within earshot of a noise it hasn't
+// already reacted to. Sound carries through walls and doors.
+func (m *Monster) hears(level *Level) bool {
+	if level.Noise == 0 || m.heardNoise == level.noiseID {
+		return false
+	}
+	if distSq(m.Pos, level.NoisePos) > level.Noise*level.Noise {
+		return false
+	}
+	m.heardNoise = level.noiseID
+	return true
+}
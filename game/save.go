@@ -0,0 +1,197 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// saveVersion is bumped whenever saveFile, or anything saved in it, gains
+// or changes a field: a save written without the field would load it as
+// zero. Version 3 added effects, the field of view, the message history and
+// the adventure count.
+const saveVersion = 3
+
+const saveFileName = "gorpg.sav"
+
+type saveFile struct {
+	Version      int
+	Seed         int64
+	Draws        uint64
+	FOV          string
+	CurrentLevel string
+	Player       *Player
+	Levels       []*levelSave
+	Messages     []Message
+	Adventure    int
+}
+
+type levelSave struct {
+	Name     string
+	Map      [][]Tile
+	Monsters []*Monster
+	Items    []*Item
+	Portals  []portalSave
+	Events   []string
+	EventPos int
+}
+
+type portalSave struct {
+	Pos
+	Level string
+	To    Pos
+}
+
+// Save writes the state of every level, the player, the portal links
+// between levels and the position of the random number generator to w.
+func (game *Game) Save(w io.Writer) error {
+	save := saveFile{
+		Version:      saveVersion,
+		Seed:         game.Seed,
+		Draws:        game.source.draws,
+		FOV:          game.FOV.Name(),
+		CurrentLevel: game.CurrentLevel.Name,
+		Player:       game.CurrentLevel.Player,
+		Messages:     game.Messages,
+		Adventure:    game.Adventure,
+	}
+
+	names := make([]string, 0, len(game.Levels))
+	for name := range game.Levels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		level := game.Levels[name]
+		ls := &levelSave{
+			Name:     name,
+			Map:      level.Map,
+			Events:   level.Events,
+			EventPos: level.EventPos,
+			Monsters: level.sortedMonsters(),
+		}
+		itemPositions := make([]Pos, 0, len(level.Items))
+		for pos := range level.Items {
+			itemPositions = append(itemPositions, pos)
+		}
+		sort.Slice(itemPositions, func(i, j int) bool {
+			return posLess(itemPositions[i], itemPositions[j])
+		})
+		for _, pos := range itemPositions {
+			ls.Items = append(ls.Items, level.Items[pos]...)
+		}
+		for pos, lp := range level.Portals {
+			ls.Portals = append(ls.Portals, portalSave{pos, lp.Level.Name, lp.Pos})
+		}
+		sort.Slice(ls.Portals, func(i, j int) bool {
+			return posLess(ls.Portals[i].Pos, ls.Portals[j].Pos)
+		})
+		save.Levels = append(save.Levels, ls)
+	}
+
+	encoder := json.NewEncoder(w)
+	return encoder.Encode(&save)
+}
+
+// Load reads a game written by Save. The returned game has no channels
+// attached; callers that already own a running game should take over its
+// Levels and CurrentLevel.
+func Load(r io.Reader) (*Game, error) {
+	var save saveFile
+	err := json.NewDecoder(r).Decode(&save)
+	if err != nil {
+		return nil, err
+	}
+	if save.Version != saveVersion {
+		return nil, fmt.Errorf("unsupported save version %d (want %d)", save.Version, saveVersion)
+	}
+	if save.Player == nil {
+		return nil, fmt.Errorf("save has no player")
+	}
+
+	levels := make(map[string]*Level)
+	for _, ls := range save.Levels {
+		level := newLevel(ls.Name, 0, 0, save.Player)
+		level.Map = ls.Map
+		if len(ls.Events) > 0 {
+			level.Events = ls.Events
+		}
+		level.EventPos = ls.EventPos
+		if level.EventPos < 0 || level.EventPos >= len(level.Events) {
+			return nil, fmt.Errorf("level %s: event position %d out of range", ls.Name, ls.EventPos)
+		}
+		for _, monster := range ls.Monsters {
+			level.Monsters[monster.Pos] = monster
+		}
+		for _, item := range ls.Items {
+			level.Items[item.Pos] = append(level.Items[item.Pos], item)
+		}
+		levels[ls.Name] = level
+	}
+
+	for _, ls := range save.Levels {
+		for _, portal := range ls.Portals {
+			to := levels[portal.Level]
+			if to == nil {
+				return nil, fmt.Errorf("level %s: portal to unknown level %s", ls.Name, portal.Level)
+			}
+			levels[ls.Name].Portals[portal.Pos] = &LevelPos{to, portal.To}
+		}
+	}
+
+	game := &Game{FOV: defaultFOV, MaxMessages: DefaultMaxMessages, Messages: save.Messages, Adventure: save.Adventure}
+	if save.FOV != "" {
+		game.FOV, err = ParseFOV(save.FOV)
+		if err != nil {
+			return nil, err
+		}
+	}
+	game.seedRNG(save.Seed, save.Draws)
+	game.setLevels(levels)
+	game.CurrentLevel = levels[save.CurrentLevel]
+	if game.CurrentLevel == nil {
+		return nil, fmt.Errorf("save has unknown current level %s", save.CurrentLevel)
+	}
+	return game, nil
+}
+
+func (game *Game) saveToFile() {
+	file, err := os.Create(saveFileName)
+	if err != nil {
+		game.CurrentLevel.AddEvent(System, "Could not save game: "+err.Error())
+		return
+	}
+	defer file.Close()
+
+	err = game.Save(file)
+	if err != nil {
+		game.CurrentLevel.AddEvent(System, "Could not save game: "+err.Error())
+		return
+	}
+	game.CurrentLevel.AddEvent(System, "Game saved")
+}
+
+func (game *Game) loadFromFile() {
+	file, err := os.Open(saveFileName)
+	if err != nil {
+		game.CurrentLevel.AddEvent(System, "Could not load game: "+err.Error())
+		return
+	}
+	defer file.Close()
+
+	loaded, err := Load(file)
+	if err != nil {
+		game.CurrentLevel.AddEvent(System, "Could not load game: "+err.Error())
+		return
+	}
+	game.seedRNG(loaded.Seed, loaded.source.draws)
+	game.FOV = loaded.FOV
+	game.Messages = loaded.Messages
+	game.Adventure = loaded.Adventure
+	game.setLevels(loaded.Levels)
+	game.CurrentLevel = loaded.CurrentLevel
+	game.CurrentLevel.AddEvent(System, "Game loaded")
+}
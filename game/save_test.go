@@ -0,0 +1,44 @@
+package game
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSaveLoad(t *testing.T) {
+	g, err := NewGame(0, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, typ := range []InputType{Right, Right, Down, Down, Left} {
+		g.Step(&Input{Typ: typ})
+	}
+
+	var buf bytes.Buffer
+	err = g.Save(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded.CurrentLevel.Name != g.CurrentLevel.Name {
+		t.Errorf("loaded level %s, want %s", loaded.CurrentLevel.Name, g.CurrentLevel.Name)
+	}
+	if loaded.CurrentLevel.Player.Pos != g.CurrentLevel.Player.Pos {
+		t.Errorf("loaded player at %v, want %v", loaded.CurrentLevel.Player.Pos, g.CurrentLevel.Player.Pos)
+	}
+	if len(loaded.Messages) != len(g.Messages) {
+		t.Errorf("loaded %d messages, want %d", len(loaded.Messages), len(g.Messages))
+	}
+	// The random number generator carries on where it left off, so the
+	// loaded game plays out the same as the original.
+	for i := 0; i < 20; i++ {
+		want, got := g.rng.Int63(), loaded.rng.Int63()
+		if got != want {
+			t.Fatalf("draw %d after loading = %d, want %d", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package game
+
+// actionCost is the energy an actor spends on one action.
+const actionCost = 1.0
+
+// minSpeed stops a heavily slowed actor from stalling the scheduler.
+const minSpeed = 0.1
+
+// advance runs the world until it is the player's turn again. Actors build
+// up energy (ActionPoints) at their speed each tick and spend actionCost
+// per action, more for wading through slow terrain. Whoever holds the most
+// energy acts next, the player winning ties and monsters breaking ties by
+// position, so a creature twice as fast as the player acts twice for each
+// of the player's turns no matter what order the monsters are stored in.
+func (game *Game) advance() {
+	level := game.CurrentLevel
+	player := level.Player
+	level.flow = flowMaps{}
+	for !player.Dead() {
+		m := level.readiestMonster()
+		if m != nil && m.ActionPoints >= actionCost && m.ActionPoints > player.ActionPoints {
+			m.ActionPoints -= m.Update(level)
+			continue
+		}
+		if player.ActionPoints >= actionCost {
+			return
+		}
+		level.tick()
+	}
+}
+
+// readiestMonster returns the monster with the most energy.
+func (level *Level) readiestMonster() *Monster {
+	var best *Monster
+	for _, m := range level.Monsters {
+		if best == nil || m.ActionPoints > best.ActionPoints ||
+			m.ActionPoints == best.ActionPoints && posLess(m.Pos, best.Pos) {
+			best = m
+		}
+	}
+	return best
+}
+
+// tick gives every actor on the level energy for one unit of time and
+// counts down their status effects.
+func (level *Level) tick() {
+	player := level.Player
+	player.ActionPoints += player.speed()
+	level.tickEffects(&player.Character)
+	for _, m := range level.sortedMonsters() {
+		m.ActionPoints += m.speed()
+		level.tickEffects(&m.Character)
+	}
+}
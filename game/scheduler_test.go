@@ -0,0 +1,75 @@
+package game
+
+import "testing"
+
+// schedulerLevel is a level of wall with a floor tile for the player at
+// (1, 1) and one for each monster, so that nobody can see or reach anyone
+// else.
+func schedulerLevel(monsters ...*Monster) *Game {
+	level := newLevel("test", 12, 3, newPlayer())
+	for y := range level.Map {
+		for x := range level.Map[y] {
+			level.Map[y][x] = Tile{Rune: StoneWall, OverlayRune: Blank}
+		}
+	}
+	level.Player.Pos = Pos{1, 1}
+	level.Map[1][1].Rune = DirtFloor
+	for _, m := range monsters {
+		level.Map[m.Y][m.X].Rune = DirtFloor
+		level.Monsters[m.Pos] = m
+	}
+
+	game := &Game{FOV: defaultFOV}
+	game.seedRNG(1, 0)
+	game.setLevels(map[string]*Level{level.Name: level})
+	game.CurrentLevel = level
+	return game
+}
+
+func testMonster(pos Pos, speed float64) *Monster {
+	kind := &MonsterKind{Rune: 'r', Name: "Rat", Hitpoints: 5, Speed: speed, SightRange: 5, Behaviour: Stationary}
+	return NewMonster(kind, pos)
+}
+
+func TestSchedulerSpeed(t *testing.T) {
+	fast := testMonster(Pos{4, 1}, 2)
+	slow := testMonster(Pos{7, 1}, 1)
+	game := schedulerLevel(fast, slow)
+	player := game.CurrentLevel.Player
+
+	// A monster that can't find the player counts the turns it has been
+	// lost, once for every action it takes.
+	const turns = 10
+	for i := 0; i < turns; i++ {
+		player.ActionPoints -= actionCost
+		game.advance()
+	}
+	// The player starts with enough energy for a turn and wins ties, so
+	// each monster is one action short of its share.
+	if fast.Lost != 2*turns-1 {
+		t.Errorf("speed 2 monster acted %d times in %d turns, want %d", fast.Lost, turns, 2*turns-1)
+	}
+	if slow.Lost != turns-1 {
+		t.Errorf("speed 1 monster acted %d times in %d turns, want %d", slow.Lost, turns, turns-1)
+	}
+}
+
+func TestReadiestMonsterTies(t *testing.T) {
+	positions := []Pos{{9, 1}, {3, 1}, {6, 1}}
+	var monsters []*Monster
+	for _, pos := range positions {
+		monsters = append(monsters, testMonster(pos, 1))
+	}
+	game := schedulerLevel(monsters...)
+	level := game.CurrentLevel
+	for _, m := range monsters {
+		m.ActionPoints = 1
+	}
+	if m := level.readiestMonster(); m.Pos != (Pos{3, 1}) {
+		t.Errorf("tie went to the monster at %v, want %v", m.Pos, Pos{3, 1})
+	}
+	monsters[2].ActionPoints = 1.5
+	if m := level.readiestMonster(); m.Pos != (Pos{6, 1}) {
+		t.Errorf("readiest monster is at %v, want %v", m.Pos, Pos{6, 1})
+	}
+}
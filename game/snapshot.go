@@ -0,0 +1,66 @@
+package game
+
+// snapshot returns a copy of the level that the game never changes, for
+// the frontends to draw on their own goroutines while the game carries on.
+// Everything the game goes on to change is copied; the portals never change
+// once the world is built, so they are shared.
+func (level *Level) snapshot() *Level {
+	snap := *level
+	snap.Map = make([][]Tile, len(level.Map))
+	for y, row := range level.Map {
+		snap.Map[y] = append([]Tile(nil), row...)
+	}
+
+	player := *level.Player
+	player.Character = level.Player.Character.snapshot()
+	snap.Player = &player
+
+	snap.Monsters = make(map[Pos]*Monster, len(level.Monsters))
+	for pos, m := range level.Monsters {
+		monster := *m
+		monster.Character = m.Character.snapshot()
+		monster.sight = nil
+		snap.Monsters[pos] = &monster
+	}
+
+	snap.Items = make(map[Pos][]*Item, len(level.Items))
+	for pos, items := range level.Items {
+		snap.Items[pos] = snapshotItems(items)
+	}
+
+	snap.Debug = make(map[Pos]bool, len(level.Debug))
+	for pos, debug := range level.Debug {
+		snap.Debug[pos] = debug
+	}
+	snap.Events = append([]string(nil), level.Events...)
+	snap.flow = flowMaps{}
+
+	// Messages are only ever appended or dropped from the front, so the
+	// snapshot can share them up to its own length.
+	snap.game = nil
+	if level.game != nil {
+		messages := level.game.Messages
+		snap.history = messages[:len(messages):len(messages)]
+	}
+	return &snap
+}
+
+func (c Character) snapshot() Character {
+	c.Inventory = snapshotItems(c.Inventory)
+	effects := c.Effects
+	c.Effects = nil
+	for _, effect := range effects {
+		e := *effect
+		c.Effects = append(c.Effects, &e)
+	}
+	return c
+}
+
+func snapshotItems(items []*Item) []*Item {
+	var snap []*Item
+	for _, item := range items {
+		i := *item
+		snap = append(snap, &i)
+	}
+	return snap
+}
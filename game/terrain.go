@@ -0,0 +1,35 @@
+package game
+
+// Terrain that slows down whoever crosses it. Like DirtFloor these are the
+// base Rune of a tile, and can be seen through and walked on.
+const (
+	Water  rune = '~'
+	Rubble rune = ':'
+	Mud    rune = ','
+)
+
+// normalTerrain is the cost of stepping onto ordinary floor.
+const normalTerrain = 100
+
+// terrainCosts is the cost of stepping onto each kind of slow terrain as a
+// percentage of an ordinary step. Anything not listed costs normalTerrain.
+var terrainCosts = map[rune]int{
+	Water:  200,
+	Rubble: 150,
+	Mud:    300,
+}
+
+func terrainCost(level *Level, pos Pos) int {
+	if inRange(level, pos) {
+		cost, exists := terrainCosts[level.Map[pos.Y][pos.X].Rune]
+		if exists {
+			return cost
+		}
+	}
+	return normalTerrain
+}
+
+// stepEnergy is the energy an actor spends moving onto pos.
+func stepEnergy(level *Level, pos Pos) float64 {
+	return actionCost * float64(terrainCost(level, pos)) / normalTerrain
+}
@@ -0,0 +1,151 @@
+package game
+
+import "time"
+
+// travelDelay is how long Run waits between the steps of an auto-explore or
+// travel-to, so that frontends can show the walk.
+const travelDelay = 40 * time.Millisecond
+
+// travel is an auto-explore or travel-to in progress.
+type travel struct {
+	explore bool
+	target  Pos
+}
+
+// Traveling reports whether the player is exploring or travelling and
+// StepTravel should be called to take the next step.
+func (game *Game) Traveling() bool {
+	return game.travel != nil
+}
+
+func (game *Game) stopTravel() {
+	game.travel = nil
+}
+
+// StepTravel takes the next step of an auto-explore or travel-to and lets
+// the monsters move, as Step does for any other input.
+func (game *Game) StepTravel() *Level {
+	if game.travel == nil {
+		return game.CurrentLevel
+	}
+	return game.takeTurn(game.travelStep)
+}
+
+// startTravel begins exploring or travelling and takes the first step. It
+// refuses while a monster is in view.
+func (game *Game) startTravel(t *travel) float64 {
+	level := game.CurrentLevel
+	for _, m := range level.sortedMonsters() {
+		if level.Map[m.Y][m.X].Visible {
+			level.AddEvent(System, "Not with "+m.Name+" in view")
+			return 0
+		}
+	}
+	if !t.explore && (!inRange(level, t.target) || !level.Map[t.target.Y][t.target.X].Seen) {
+		level.AddEvent(System, "You don't know the way there")
+		return 0
+	}
+	game.travel = t
+	return game.travelStep()
+}
+
+// travelToStair travels to the level's up or down stair, if the player has
+// seen it.
+func (game *Game) travelToStair(stair rune) float64 {
+	level := game.CurrentLevel
+	for y, row := range level.Map {
+		for x, tile := range row {
+			if tile.OverlayRune == stair && tile.Seen {
+				return game.startTravel(&travel{target: Pos{x, y}})
+			}
+		}
+	}
+	level.AddEvent(System, "You haven't found the way there")
+	return 0
+}
+
+// travelStep moves the player one tile further and returns the energy it
+// cost, or 0 if it didn't take a turn. It stops travelling once there is
+// nowhere left to go.
+func (game *Game) travelStep() float64 {
+	level := game.CurrentLevel
+	p := level.Player
+	var next Pos
+	if game.travel.explore {
+		var ok bool
+		next, ok = level.exploreStep()
+		if !ok {
+			if level.towardUnexplored().at(p.Pos) == unreachable {
+				level.AddEvent(Discovery, "Nothing left to explore")
+			} else {
+				level.AddEvent(System, "Something is in the way")
+			}
+			game.stopTravel()
+			return 0
+		}
+	} else {
+		if p.Pos == game.travel.target {
+			game.stopTravel()
+			return 0
+		}
+		path := level.astar(p.Pos, game.travel.target)
+		if len(path) < 2 {
+			level.AddEvent(System, "You can't find a way there")
+			game.stopTravel()
+			return 0
+		}
+		next = path[1]
+	}
+	return game.resolveMovement(next)
+}
+
+// exploreStep picks the neighbouring tile that leads most quickly to ground
+// the player hasn't seen yet. Closed doors count, since walking into one
+// opens it.
+func (level *Level) exploreStep() (Pos, bool) {
+	dm := level.towardUnexplored()
+	from := level.Player.Pos
+	best := from
+	bestCost := dm.at(from)
+	for _, dir := range compass {
+		next := Pos{from.X + dir.X, from.Y + dir.Y}
+		if !level.explorerPassable(next) || diagonalBlocked(level, from, next) {
+			continue
+		}
+		if _, exists := level.Monsters[next]; exists {
+			continue
+		}
+		cost := dm.at(next)
+		if cost < bestCost {
+			best = next
+			bestCost = cost
+		}
+	}
+	return best, best != from
+}
+
+// visibleMonsters returns the monsters the player can currently see.
+func (level *Level) visibleMonsters() map[*Monster]bool {
+	visible := make(map[*Monster]bool)
+	for pos, m := range level.Monsters {
+		if level.Map[pos.Y][pos.X].Visible {
+			visible[m] = true
+		}
+	}
+	return visible
+}
+
+// interrupted reports whether anything happened during the last turn that
+// should stop the player travelling: a change of level, a new event or a
+// monster coming into view.
+func (game *Game) interrupted(level *Level, events int, visible map[*Monster]bool) bool {
+	if game.CurrentLevel != level || level.eventCount != events {
+		return true
+	}
+	for m := range level.visibleMonsters() {
+		if !visible[m] {
+			return true
+		}
+	}
+	return false
+}
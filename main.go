@@ -1,8 +1,11 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"os"
 	"runtime"
+	"time"
 
 	"github.com/michaelilao/gorpg/game"
 	"github.com/michaelilao/gorpg/ui"
@@ -11,7 +14,23 @@ import (
 const numWindows = 1
 
 func main() {
-	game := game.NewGame(numWindows)
+	seed := flag.Int64("seed", time.Now().UnixNano(), "random seed, to replay a previous game")
+	fovName := flag.String("fov", "shadowcast", "field of view: ray, shadowcast or permissive")
+	history := flag.Int("history", game.DefaultMaxMessages, "how many messages the log keeps, or 0 to keep them all")
+	flag.Parse()
+
+	fov, err := game.ParseFOV(*fovName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	game, err := game.NewGame(numWindows, *seed)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	game.SetFOV(fov)
+	game.MaxMessages = *history
 
 	for i := 0; i < numWindows; i++ {
 		go func(i int) {
@@ -21,5 +40,5 @@ func main() {
 		}(i)
 	}
 	game.Run()
-	fmt.Println("Done")
+	fmt.Println("Done, seed", game.Seed)
 }
@@ -0,0 +1,335 @@
+// Package tui is a terminal frontend for the game. It speaks the same
+// channel protocol as the SDL ui package but draws the level with ANSI
+// escape codes, so it works over ssh and without a display.
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/michaelilao/gorpg/game"
+)
+
+const (
+	reset     = "\x1b[0m"
+	dim       = "\x1b[90m"
+	red       = "\x1b[31m"
+	yellow    = "\x1b[1;33m"
+	debugBg   = "\x1b[41m"
+	clearHome = "\x1b[H\x1b[2J"
+	hideCur   = "\x1b[?25l"
+	showCur   = "\x1b[?25h"
+)
+
+const eventLines = 10
+
+type ui struct {
+	levelChan chan *game.Level
+	inputChan chan *game.Input
+	out       *bufio.Writer
+	sttyState string
+	width     int
+	height    int
+}
+
+func NewUI(inputChan chan *game.Input, levelChan chan *game.Level) *ui {
+	ui := &ui{}
+	ui.inputChan = inputChan
+	ui.levelChan = levelChan
+	ui.out = bufio.NewWriter(os.Stdout)
+	ui.width, ui.height = terminalSize()
+	return ui
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+func terminalSize() (int, int) {
+	size, err := stty("size")
+	if err == nil {
+		fields := strings.Fields(size)
+		if len(fields) == 2 {
+			rows, err1 := strconv.Atoi(fields[0])
+			cols, err2 := strconv.Atoi(fields[1])
+			if err1 == nil && err2 == nil && rows > 0 && cols > 0 {
+				return cols, rows
+			}
+		}
+	}
+	return 80, 24
+}
+
+func (ui *ui) enterRawMode() {
+	state, err := stty("-g")
+	if err != nil {
+		panic(err)
+	}
+	ui.sttyState = state
+	_, err = stty("raw", "-echo")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Fprint(os.Stdout, hideCur)
+}
+
+func (ui *ui) restore() {
+	if ui.sttyState == "" {
+		return
+	}
+	stty(ui.sttyState)
+	ui.sttyState = ""
+	fmt.Fprint(os.Stdout, reset+showCur+"\r\n")
+}
+
+// readKeys turns raw bytes from stdin into key names such as "up" or "k".
+func readKeys(keys chan<- string) {
+	buf := make([]byte, 16)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			close(keys)
+			return
+		}
+		in := buf[:n]
+		for len(in) > 0 {
+			if in[0] == 0x1b && len(in) >= 3 && in[1] == '[' {
+				switch in[2] {
+				case 'A':
+					keys <- "up"
+				case 'B':
+					keys <- "down"
+				case 'C':
+					keys <- "right"
+				case 'D':
+					keys <- "left"
+				}
+				if in[2] >= 'A' && in[2] <= 'D' {
+					in = in[3:]
+					continue
+				}
+				// Function keys look like ESC [ 1 5 ~
+				end := 2
+				for end < len(in) && in[end] >= '0' && in[end] <= '9' {
+					end++
+				}
+				if end < len(in) && in[end] == '~' {
+					keys <- string(in[:end+1])
+					end++
+				}
+				in = in[end:]
+				continue
+			}
+			keys <- string(in[0])
+			in = in[1:]
+		}
+	}
+}
+
+func keyToInput(key string) game.InputType {
+	switch key {
+	case "up", "k":
+		return game.Up
+	case "down", "j":
+		return game.Down
+	case "left", "h":
+		return game.Left
+	case "right", "l":
+		return game.Right
+	case "y":
+		return game.UpLeft
+	case "u":
+		return game.UpRight
+	case "b":
+		return game.DownLeft
+	case "n":
+		return game.DownRight
+	case "o":
+		return game.Explore
+	case ">":
+		return game.TravelDownStair
+	case "<":
+		return game.TravelUpStair
+	case "g":
+		return game.PickUp
+	case "x":
+		return game.Drop
+	case "e":
+		return game.Use
+	case "f":
+		return game.Equip
+	case "r":
+		return game.Unequip
+	case "]":
+		return game.SelectNext
+	case "[":
+		return game.SelectPrev
+	case "\r":
+		return game.Restart
+	case "\x1b[15~": // F5
+		return game.SaveGame
+	case "\x1b[20~": // F9
+		return game.LoadGame
+	case "q", "\x03":
+		return game.QuitGame
+	}
+	return game.None
+}
+
+func (ui *ui) Draw(level *game.Level) {
+	mapHeight := ui.height - eventLines - 2
+	if mapHeight < 1 {
+		mapHeight = 1
+	}
+	startX := level.Player.X - ui.width/2
+	startY := level.Player.Y - mapHeight/2
+
+	var sb strings.Builder
+	sb.WriteString(clearHome)
+	for y := startY; y < startY+mapHeight; y++ {
+		colour := ""
+		for x := startX; x < startX+ui.width; x++ {
+			r, c := ui.cell(level, game.Pos{X: x, Y: y})
+			if c != colour {
+				sb.WriteString(reset + c)
+				colour = c
+			}
+			sb.WriteRune(r)
+		}
+		sb.WriteString(reset + "\r\n")
+	}
+	if level.Player.Dead() {
+		sb.WriteString(yellow + deathLine(level.Player) + reset + "\r\n")
+	} else {
+		sb.WriteString(inventoryLine(level.Player, ui.width) + "\r\n")
+	}
+
+	i := level.EventPos
+	for {
+		event := level.Events[i]
+		if event != "" {
+			if len(event) > ui.width {
+				event = event[:ui.width]
+			}
+			sb.WriteString(red + event + reset)
+		}
+		sb.WriteString("\r\n")
+		i = (i + 1) % len(level.Events)
+		if i == level.EventPos {
+			break
+		}
+	}
+	ui.out.WriteString(sb.String())
+	ui.out.Flush()
+}
+
+// cell picks the character and colour for one map position, dimming tiles
+// that have been seen but are not currently visible.
+func (ui *ui) cell(level *game.Level, pos game.Pos) (rune, string) {
+	if pos.Y < 0 || pos.Y >= len(level.Map) || pos.X < 0 || pos.X >= len(level.Map[pos.Y]) {
+		return ' ', ""
+	}
+	tile := level.Map[pos.Y][pos.X]
+	if tile.Rune == game.Blank || !tile.Visible && !tile.Seen {
+		return ' ', ""
+	}
+	if pos == level.Player.Pos {
+		return level.Player.Rune, yellow
+	}
+	if monster, exists := level.Monsters[pos]; exists && tile.Visible {
+		return monster.Rune, red
+	}
+	if items := level.Items[pos]; len(items) > 0 {
+		if tile.Visible {
+			return items[len(items)-1].Rune, yellow
+		}
+		return items[len(items)-1].Rune, dim
+	}
+
+	r := tile.Rune
+	if tile.OverlayRune != game.Blank {
+		r = tile.OverlayRune
+	}
+	colour := ""
+	if level.Debug[pos] {
+		colour = debugBg
+	} else if !tile.Visible {
+		colour = dim
+	}
+	return r, colour
+}
+
+// inventoryLine lists the player's items on one line, bracketing the
+// selected one and starring equipped ones.
+func inventoryLine(player *game.Player, width int) string {
+	line := "Inventory:"
+	if len(player.Inventory) == 0 {
+		line += " empty"
+	}
+	for i, item := range player.Inventory {
+		name := item.Name
+		if item.Equipped {
+			name += "*"
+		}
+		if i == player.Selected {
+			line += " [" + name + "]"
+		} else {
+			line += " " + name
+		}
+	}
+	if len(line) > width {
+		line = line[:width]
+	}
+	return line
+}
+
+func deathLine(player *game.Player) string {
+	killer := player.KilledBy
+	if killer == "" {
+		killer = "misfortune"
+	}
+	return fmt.Sprintf("You were killed by %s after %d turns and %d kills. Press Enter to play again.",
+		killer, player.Turns, player.Kills)
+}
+
+func (ui *ui) Run() {
+	ui.enterRawMode()
+	defer ui.restore()
+
+	keys := make(chan string)
+	go readKeys(keys)
+
+	for {
+		select {
+		case newLevel, ok := <-ui.levelChan:
+			if !ok {
+				return
+			}
+			ui.Draw(newLevel)
+		case key, ok := <-keys:
+			if !ok {
+				ui.restore()
+				ui.inputChan <- &game.Input{Typ: game.QuitGame}
+				return
+			}
+			typ := keyToInput(key)
+			if typ == game.QuitGame {
+				// Put the terminal back before the game returns and the
+				// process exits underneath us.
+				ui.restore()
+				ui.inputChan <- &game.Input{Typ: typ}
+				return
+			}
+			if typ != game.None {
+				ui.inputChan <- &game.Input{Typ: typ}
+			}
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package ui
+
+import (
+	"math"
+
+	"github.com/michaelilao/gorpg/game"
+)
+
+// tileSizes are the zoom levels, the width and height of a map tile on
+// screen in pixels, from furthest out to closest in.
+var tileSizes = []int{16, 24, 32, 48, 64}
+
+// defaultZoom is the index into tileSizes a new window starts at.
+const defaultZoom = 2
+
+// camera decides which part of the level is on screen. The player can walk
+// deadZone tiles from the centre of the view before it follows them, and it
+// glides to its new position rather than jumping, except on a new level
+// where it snaps straight to the player. It never shows past the edge of
+// the map unless the whole map fits on screen, in which case it is centred.
+type camera struct {
+	// x and y are the point of the map, in pixels, shown at the centre of
+	// the window, and targetX and targetY where the camera is heading.
+	x, y             float64
+	targetX, targetY float64
+	levelName        string
+	tileSize         int
+	deadZone         int
+	// speed is the share of the remaining distance covered per second; 0
+	// moves the camera instantly.
+	speed    float64
+	lastTick uint32
+}
+
+// follow works out where the camera should be for the level just received.
+func (c *camera) follow(level *game.Level, winWidth, winHeight int) {
+	tileSize := c.tileSize
+	px := float64(level.Player.X*tileSize + tileSize/2)
+	py := float64(level.Player.Y*tileSize + tileSize/2)
+	snap := level.Name != c.levelName
+	c.levelName = level.Name
+	if snap {
+		c.targetX, c.targetY = px, py
+	} else {
+		zone := float64(c.deadZone * tileSize)
+		c.targetX = intoZone(c.targetX, px, zone)
+		c.targetY = intoZone(c.targetY, py, zone)
+	}
+	c.targetX = clampAxis(c.targetX, len(level.Map[0])*tileSize, winWidth)
+	c.targetY = clampAxis(c.targetY, len(level.Map)*tileSize, winHeight)
+	if snap || c.speed <= 0 {
+		c.x, c.y = c.targetX, c.targetY
+	}
+}
+
+// setTileSize changes the zoom, keeping the same point of the map in the
+// centre of the window.
+func (c *camera) setTileSize(size int) {
+	if c.tileSize != 0 {
+		scale := float64(size) / float64(c.tileSize)
+		c.x *= scale
+		c.y *= scale
+		c.targetX *= scale
+		c.targetY *= scale
+	}
+	c.tileSize = size
+}
+
+// jump moves the camera straight to its target.
+func (c *camera) jump() {
+	c.x, c.y = c.targetX, c.targetY
+}
+
+// intoZone moves centre just far enough for p to be within zone of it.
+func intoZone(centre, p, zone float64) float64 {
+	if p > centre+zone {
+		return p - zone
+	}
+	if p < centre-zone {
+		return p + zone
+	}
+	return centre
+}
+
+func clampAxis(centre float64, mapSize, screenSize int) float64 {
+	if mapSize <= screenSize {
+		return float64(mapSize) / 2
+	}
+	half := float64(screenSize) / 2
+	return math.Max(half, math.Min(float64(mapSize)-half, centre))
+}
+
+// update moves the camera towards its target for the time since the last
+// update, given in milliseconds, and reports whether it has moved.
+func (c *camera) update(now uint32) bool {
+	dt := float64(now-c.lastTick) / 1000
+	c.lastTick = now
+	if c.x == c.targetX && c.y == c.targetY {
+		return false
+	}
+	step := 1.0
+	if c.speed > 0 {
+		step = math.Min(1, c.speed*math.Min(dt, 0.1))
+	}
+	c.x += (c.targetX - c.x) * step
+	c.y += (c.targetY - c.y) * step
+	if math.Abs(c.targetX-c.x) < 0.5 && math.Abs(c.targetY-c.y) < 0.5 {
+		c.x, c.y = c.targetX, c.targetY
+	}
+	return true
+}
+
+// offsets is where the top left corner of the map is drawn on screen.
+func (c *camera) offsets(winWidth, winHeight int) (int32, int32) {
+	return int32(math.Round(float64(winWidth)/2 - c.x)), int32(math.Round(float64(winHeight)/2 - c.y))
+}
@@ -0,0 +1,69 @@
+package ui
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const defaultDisplayFile = "ui/assets/display.txt"
+
+// displayConfig is everything read from the display file: for now, how the
+// camera follows the player.
+type displayConfig struct {
+	cameraDeadZone int
+	cameraSpeed    float64
+}
+
+// Defaults for the display settings when the display file leaves them out.
+const (
+	defaultDeadZone    = 4
+	defaultCameraSpeed = 10
+)
+
+func loadDisplay(filename string) (*displayConfig, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	csvReader := csv.NewReader(file)
+	csvReader.Comment = '#'
+	csvReader.FieldsPerRecord = 2
+	csvReader.TrimLeadingSpace = true
+
+	config := &displayConfig{}
+	config.cameraDeadZone = defaultDeadZone
+	config.cameraSpeed = defaultCameraSpeed
+	for {
+		row, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := csvReader.FieldPos(0)
+		switch strings.ToLower(row[0]) {
+		case "camera dead zone":
+			zone, err := strconv.Atoi(row[1])
+			if err != nil || zone < 0 {
+				return nil, fmt.Errorf("%s:%d: bad camera dead zone %q", filename, line, row[1])
+			}
+			config.cameraDeadZone = zone
+		case "camera speed":
+			speed, err := strconv.ParseFloat(row[1], 64)
+			if err != nil || speed < 0 {
+				return nil, fmt.Errorf("%s:%d: bad camera speed %q", filename, line, row[1])
+			}
+			config.cameraSpeed = speed
+		default:
+			return nil, fmt.Errorf("%s:%d: unknown setting %q", filename, line, row[0])
+		}
+	}
+	return config, nil
+}
@@ -0,0 +1,148 @@
+package ui
+
+import (
+	"strconv"
+
+	"github.com/michaelilao/gorpg/game"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+var categoryColors = map[game.Category]sdl.Color{
+	game.System:    {200, 200, 200, 0},
+	game.Combat:    {255, 0, 0, 0},
+	game.Discovery: {255, 220, 80, 0},
+}
+
+// historyFilters are the views of the message log, in the order Left and
+// Right step through them.
+var historyFilters = []struct {
+	name     string
+	all      bool
+	category game.Category
+}{
+	{"all", true, game.System},
+	{"combat", false, game.Combat},
+	{"discovery", false, game.Discovery},
+	{"system", false, game.System},
+}
+
+// historyView is the full-screen message log.
+type historyView struct {
+	open bool
+	// scroll is how many lines the log is scrolled back from the newest
+	// message, and page how many lines fitted on screen last time it was
+	// drawn.
+	scroll int
+	page   int
+	filter int
+}
+
+// historyKeys scrolls, filters and closes the message log, and reports
+// whether anything changed. The viewer's keys are fixed rather than read
+// from the keys file, and are listed at the top of it.
+func (ui *ui) historyKeys() bool {
+	view := &ui.history
+	switch {
+	case ui.keyDownOnce(sdl.SCANCODE_ESCAPE):
+		view.open = false
+	case ui.keyDownOnce(sdl.SCANCODE_UP):
+		view.scroll++
+	case ui.keyDownOnce(sdl.SCANCODE_DOWN):
+		view.scroll--
+	case ui.keyDownOnce(sdl.SCANCODE_PAGEUP):
+		view.scroll += view.page
+	case ui.keyDownOnce(sdl.SCANCODE_PAGEDOWN):
+		view.scroll -= view.page
+	case ui.keyDownOnce(sdl.SCANCODE_HOME) && ui.level != nil:
+		view.scroll = len(ui.level.History())
+	case ui.keyDownOnce(sdl.SCANCODE_END):
+		view.scroll = 0
+	case ui.keyDownOnce(sdl.SCANCODE_LEFT):
+		view.filter = (view.filter + len(historyFilters) - 1) % len(historyFilters)
+		view.scroll = 0
+	case ui.keyDownOnce(sdl.SCANCODE_RIGHT):
+		view.filter = (view.filter + 1) % len(historyFilters)
+		view.scroll = 0
+	default:
+		return false
+	}
+	return true
+}
+
+// historyRow is a line of the message log: a message, or a heading where a
+// restarted game's messages begin.
+type historyRow struct {
+	heading string
+	msg     game.Message
+}
+
+// drawHistory lists the messages that pass the current filter over the
+// top of the game, newest at the bottom, each after the turn it happened on.
+// Turns count from 0 again after a restart, so each adventure after the
+// first starts with a heading.
+func (ui *ui) drawHistory(level *game.Level) {
+	view := &ui.history
+	filter := historyFilters[view.filter]
+	var rows []historyRow
+	adventure := 0
+	for _, msg := range level.History() {
+		if !filter.all && msg.Category != filter.category {
+			continue
+		}
+		if adventure != 0 && msg.Adventure != adventure {
+			rows = append(rows, historyRow{heading: "--- Adventure " + strconv.Itoa(msg.Adventure) + " ---"})
+		}
+		adventure = msg.Adventure
+		rows = append(rows, historyRow{msg: msg})
+	}
+
+	ui.renderer.Copy(ui.logBackground, nil, nil)
+
+	title := ui.stringToTexture("Message log: "+filter.name, sdl.Color{255, 255, 255, 0}, FontMedium)
+	_, _, w, titleHeight, err := title.Query()
+	checkError(err)
+	ui.renderer.Copy(title, nil, &sdl.Rect{(int32(ui.winWidth) - w) / 2, 10, w, titleHeight})
+
+	help := ui.stringToTexture("Left/Right filter, Up/Down/PageUp/PageDown/Home/End scroll, Escape to close", sdl.Color{255, 255, 255, 0}, FontSmall)
+	_, _, w, helpHeight, err := help.Query()
+	checkError(err)
+	ui.renderer.Copy(help, nil, &sdl.Rect{(int32(ui.winWidth) - w) / 2, 10 + titleHeight, w, helpHeight})
+
+	_, lineHeight, _ := ui.fontSmall.SizeUTF8("A")
+	turnWidth, _, _ := ui.fontSmall.SizeUTF8("Turn 00000  ")
+	top := int(20 + titleHeight + helpHeight)
+	view.page = (ui.winHeight - top - 10) / lineHeight
+	if view.page < 1 {
+		view.page = 1
+	}
+	maxScroll := len(rows) - view.page
+	if view.scroll > maxScroll {
+		view.scroll = maxScroll
+	}
+	if view.scroll < 0 {
+		view.scroll = 0
+	}
+
+	end := len(rows) - view.scroll
+	start := end - view.page
+	if start < 0 {
+		start = 0
+	}
+	for i, row := range rows[start:end] {
+		y := int32(top + i*lineHeight)
+		if row.heading != "" {
+			tex := ui.stringToTexture(row.heading, sdl.Color{255, 255, 255, 0}, FontSmall)
+			_, _, w, h, err := tex.Query()
+			checkError(err)
+			ui.renderer.Copy(tex, nil, &sdl.Rect{10, y, w, h})
+			continue
+		}
+		msg := row.msg
+		color := categoryColors[msg.Category]
+		ui.drawString("Turn "+strconv.Itoa(msg.Turn), sdl.Color{160, 160, 160, 0}, FontSmall, 10, y)
+		text := ui.stringToTexture(msg.Text, color, FontSmall)
+		_, _, w, h, err := text.Query()
+		checkError(err)
+		ui.renderer.Copy(text, nil, &sdl.Rect{int32(10 + turnWidth), y, w, h})
+	}
+}
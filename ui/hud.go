@@ -0,0 +1,53 @@
+package ui
+
+import (
+	"strconv"
+
+	"github.com/michaelilao/gorpg/game"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// drawHUD shows the level name, turn counter, the player's hitpoints as a
+// bar, their stats and any effects on them in the top left corner.
+func (ui *ui) drawHUD(level *game.Level) {
+	player := level.Player
+	_, lineHeight, _ := ui.fontSmall.SizeUTF8("A")
+	panelWidth := int32(float64(ui.winWidth) * .2)
+
+	lines := []string{
+		level.Name + "  Turn " + strconv.Itoa(player.Turns),
+		"HP " + strconv.Itoa(player.Hitpoints) + "/" + strconv.Itoa(player.MaxHitpoints),
+		"Str " + strconv.Itoa(player.Strength) +
+			"  Spd " + strconv.FormatFloat(player.Speed, 'f', 1, 64) +
+			"  AP " + strconv.FormatFloat(player.ActionPoints, 'f', 1, 64),
+	}
+	for _, effect := range player.Effects {
+		lines = append(lines, effect.Name+" ("+strconv.Itoa(effect.Ticks)+")")
+	}
+	ui.renderer.Copy(ui.eventBackground, nil, &sdl.Rect{0, 0, panelWidth, int32(len(lines)*lineHeight + 4)})
+
+	// The hitpoints line is written over the bar.
+	barRect := sdl.Rect{5, int32(lineHeight) + 2, panelWidth - 10, int32(lineHeight) - 4}
+	ui.whitePixel.SetColorMod(64, 0, 0)
+	ui.renderer.Copy(ui.whitePixel, nil, &barRect)
+	if player.MaxHitpoints > 0 && player.Hitpoints > 0 {
+		fraction := float64(player.Hitpoints) / float64(player.MaxHitpoints)
+		if fraction > 1 {
+			fraction = 1
+		}
+		r, g := uint8(0), uint8(160)
+		if fraction <= .25 {
+			r, g = 200, 0
+		} else if fraction <= .5 {
+			r, g = 200, 160
+		}
+		ui.whitePixel.SetColorMod(r, g, 0)
+		barRect.W = int32(float64(barRect.W) * fraction)
+		ui.renderer.Copy(ui.whitePixel, nil, &barRect)
+	}
+
+	// These change from turn to turn, so they aren't worth caching.
+	for i, line := range lines {
+		ui.drawString(line, sdl.Color{255, 255, 255, 0}, FontSmall, 10, int32(i*lineHeight))
+	}
+}
@@ -0,0 +1,255 @@
+package ui
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/michaelilao/gorpg/game"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+const defaultKeysFile = "ui/assets/keys.txt"
+
+// uiAction is what a key binding does. Most bindings send their input to
+// the game; the rest are handled by the window itself.
+type uiAction int
+
+const (
+	sendInput uiAction = iota
+	showBindings
+	toggleFullscreen
+	zoomIn
+	zoomOut
+	toggleMinimap
+	showHistory
+)
+
+var actionNames = map[string]uiAction{
+	"bindings":   showBindings,
+	"fullscreen": toggleFullscreen,
+	"zoomin":     zoomIn,
+	"zoomout":    zoomOut,
+	"minimap":    toggleMinimap,
+	"history":    showHistory,
+}
+
+// target is the name of the input or action b is bound to in the keys file.
+func (b binding) target() string {
+	if b.action == sendInput {
+		return b.input.String()
+	}
+	for name, action := range actionNames {
+		if action == b.action {
+			return name
+		}
+	}
+	return ""
+}
+
+// Modifier keys a binding can require. Left and right keys count the same.
+const (
+	modShift = 1 << iota
+	modCtrl
+	modAlt
+)
+
+var modNames = map[string]int{
+	"shift": modShift,
+	"ctrl":  modCtrl,
+	"alt":   modAlt,
+}
+
+type binding struct {
+	scancode sdl.Scancode
+	mods     int
+	action   uiAction
+	// input is what a sendInput binding sends to the game.
+	input game.InputType
+}
+
+// String writes the binding the way it appears in the keys file.
+func (b binding) String() string {
+	s := ""
+	if b.mods&modShift != 0 {
+		s += "Shift+"
+	}
+	if b.mods&modCtrl != 0 {
+		s += "Ctrl+"
+	}
+	if b.mods&modAlt != 0 {
+		s += "Alt+"
+	}
+	return s + sdl.GetScancodeName(b.scancode)
+}
+
+// configFile is the user's own copy of the named settings file, e.g.
+// "keys.txt", if they have one, and the default shipped with the game
+// otherwise.
+func configFile(name, shipped string) string {
+	dir, err := os.UserConfigDir()
+	if err == nil {
+		filename := filepath.Join(dir, "gorpg", name)
+		if _, err := os.Stat(filename); err == nil {
+			return filename
+		}
+	}
+	return shipped
+}
+
+// keyConfig is everything read from the keys file: the bindings and the
+// settings for key repeat.
+type keyConfig struct {
+	bindings []binding
+	// repeatDelay is how long a movement key has to be held before it
+	// starts repeating, and repeatInterval the time between repeats, both in
+	// milliseconds.
+	repeatDelay    uint32
+	repeatInterval uint32
+}
+
+// Defaults for the repeat settings when the keys file leaves them out.
+const (
+	defaultRepeatDelay = 250
+	defaultRepeatRate  = 10
+)
+
+func loadKeys(filename string) (*keyConfig, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	csvReader := csv.NewReader(file)
+	csvReader.Comment = '#'
+	csvReader.FieldsPerRecord = 2
+	csvReader.TrimLeadingSpace = true
+
+	config := &keyConfig{}
+	config.repeatDelay = defaultRepeatDelay
+	config.repeatInterval = 1000 / defaultRepeatRate
+	for {
+		row, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := csvReader.FieldPos(0)
+		switch strings.ToLower(row[0]) {
+		case "repeat delay":
+			delay, err := strconv.Atoi(row[1])
+			if err != nil || delay < 0 {
+				return nil, fmt.Errorf("%s:%d: bad repeat delay %q", filename, line, row[1])
+			}
+			config.repeatDelay = uint32(delay)
+			continue
+		case "repeat rate":
+			rate, err := strconv.Atoi(row[1])
+			if err != nil || rate < 1 || rate > 1000 {
+				return nil, fmt.Errorf("%s:%d: bad repeat rate %q", filename, line, row[1])
+			}
+			config.repeatInterval = uint32(1000 / rate)
+			continue
+		}
+		b, err := parseBinding(row)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", filename, line, err)
+		}
+		config.bindings = append(config.bindings, b)
+	}
+	return config, nil
+}
+
+func parseBinding(row []string) (binding, error) {
+	var b binding
+	key := strings.TrimSpace(row[0])
+	for {
+		prefix, rest, found := strings.Cut(key, "+")
+		flag, isMod := modNames[strings.ToLower(strings.TrimSpace(prefix))]
+		if !found || !isMod || rest == "" {
+			break
+		}
+		b.mods |= flag
+		key = rest
+	}
+	b.scancode = sdl.GetScancodeFromName(key)
+	if b.scancode == sdl.SCANCODE_UNKNOWN {
+		return b, fmt.Errorf("unknown key %q", key)
+	}
+
+	name := strings.TrimSpace(row[1])
+	if action, ok := actionNames[strings.ToLower(name)]; ok {
+		b.action = action
+		return b, nil
+	}
+	typ, err := game.ParseInputType(name)
+	if err != nil {
+		return b, err
+	}
+	if typ.NeedsTarget() {
+		return b, fmt.Errorf("%q needs a target and cannot be bound to a key", name)
+	}
+	b.input = typ
+	return b, nil
+}
+
+// currentMods returns the modifier keys being held down.
+func currentMods() int {
+	state := sdl.GetModState()
+	mods := 0
+	if state&sdl.KMOD_SHIFT != 0 {
+		mods |= modShift
+	}
+	if state&sdl.KMOD_CTRL != 0 {
+		mods |= modCtrl
+	}
+	if state&sdl.KMOD_ALT != 0 {
+		mods |= modAlt
+	}
+	return mods
+}
+
+// boundInput returns the first binding whose key was just pressed with
+// exactly its modifiers held, if there is one.
+func (ui *ui) boundInput() (binding, bool) {
+	mods := currentMods()
+	for _, b := range ui.keys.bindings {
+		if b.mods == mods && ui.keyDownOnce(b.scancode) {
+			return b, true
+		}
+	}
+	return binding{}, false
+}
+
+// drawBindings lists every key binding over the top of the game.
+func (ui *ui) drawBindings() {
+	ui.renderer.Copy(ui.eventBackground, nil, nil)
+
+	title := ui.stringToTexture("Key bindings (Escape to close)", sdl.Color{255, 255, 255, 0}, FontMedium)
+	_, _, w, h, err := title.Query()
+	checkError(err)
+	ui.renderer.Copy(title, nil, &sdl.Rect{(int32(ui.winWidth) - w) / 2, 10, w, h})
+
+	_, lineHeight, _ := ui.fontSmall.SizeUTF8("A")
+	top := 20 + h
+	rows := (int32(ui.winHeight) - top) / int32(lineHeight)
+	if rows < 1 {
+		rows = 1
+	}
+	columnWidth := int32(ui.winWidth) / 4
+	for i, b := range ui.keys.bindings {
+		tex := ui.stringToTexture(b.String()+": "+b.target(), sdl.Color{255, 255, 255, 0}, FontSmall)
+		_, _, w, h, err := tex.Query()
+		checkError(err)
+		column := int32(i) / rows
+		row := int32(i) % rows
+		ui.renderer.Copy(tex, nil, &sdl.Rect{10 + column*columnWidth, top + row*int32(lineHeight), w, h})
+	}
+}
@@ -0,0 +1,139 @@
+package ui
+
+import (
+	"github.com/michaelilao/gorpg/game"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// minimapColors are the colours of seen tiles on the minimap, by overlay
+// rune first and then by tile rune. Anything not listed is drawn as floor.
+var minimapColors = map[rune]sdl.Color{
+	game.StoneWall: {110, 110, 110, 255},
+	game.DirtFloor: {50, 45, 40, 255},
+	game.Water:     {40, 70, 160, 255},
+	game.Rubble:    {90, 75, 55, 255},
+	game.Mud:       {70, 55, 30, 255},
+	game.CloseDoor: {170, 110, 40, 255},
+	game.OpenDoor:  {170, 110, 40, 255},
+	game.UpStair:   {230, 220, 80, 255},
+	game.DownStair: {230, 220, 80, 255},
+}
+
+// minimap keeps a texture with one pixel for every tile of the level. Only
+// the pixels of tiles that have changed since the last update are written,
+// so it costs little to keep up to date as the player explores.
+type minimap struct {
+	levelName string
+	texture   *sdl.Texture
+	pixels    []byte
+	// drawn is the tile each pixel was last drawn from, with Visible
+	// cleared since the minimap only shows what has been seen. Comparing
+	// whole tiles also catches tiles going back to unseen when a game is
+	// restarted or loaded.
+	drawn  []game.Tile
+	width  int
+	height int
+}
+
+// update brings the texture up to date with level, starting again from
+// scratch on a different level.
+func (m *minimap) update(renderer *sdl.Renderer, level *game.Level) {
+	if level.Name != m.levelName || len(level.Map) != m.height || len(level.Map[0]) != m.width {
+		if m.texture != nil {
+			m.texture.Destroy()
+		}
+		m.levelName = level.Name
+		m.height = len(level.Map)
+		m.width = len(level.Map[0])
+		var err error
+		m.texture, err = renderer.CreateTexture(sdl.PIXELFORMAT_ABGR8888, sdl.TEXTUREACCESS_STREAMING, int32(m.width), int32(m.height))
+		checkError(err)
+		m.texture.SetBlendMode(sdl.BLENDMODE_BLEND)
+		m.pixels = make([]byte, m.width*m.height*4)
+		m.drawn = make([]game.Tile, m.width*m.height)
+		m.texture.Update(nil, m.pixels, m.width*4)
+	}
+
+	minX, minY, maxX, maxY := m.width, m.height, -1, -1
+	for y, row := range level.Map {
+		for x, tile := range row {
+			tile.Visible = false
+			i := y*m.width + x
+			if tile == m.drawn[i] {
+				continue
+			}
+			m.drawn[i] = tile
+			var color sdl.Color
+			if tile.Seen {
+				var exists bool
+				color, exists = minimapColors[tile.OverlayRune]
+				if !exists {
+					color, exists = minimapColors[tile.Rune]
+				}
+				if !exists {
+					color = minimapColors[game.DirtFloor]
+				}
+			}
+			m.pixels[i*4] = color.R
+			m.pixels[i*4+1] = color.G
+			m.pixels[i*4+2] = color.B
+			m.pixels[i*4+3] = color.A
+			if x < minX {
+				minX = x
+			}
+			if x > maxX {
+				maxX = x
+			}
+			if y < minY {
+				minY = y
+			}
+			if y > maxY {
+				maxY = y
+			}
+		}
+	}
+	if maxX >= 0 {
+		rect := sdl.Rect{int32(minX), int32(minY), int32(maxX - minX + 1), int32(maxY - minY + 1)}
+		m.texture.Update(&rect, m.pixels[(minY*m.width+minX)*4:], m.width*4)
+	}
+}
+
+// drawMinimap shows the explored part of the level in the bottom right
+// corner, with the player and the monsters in view marked on it.
+func (ui *ui) drawMinimap(level *game.Level) {
+	ui.minimap.update(ui.renderer, level)
+	m := &ui.minimap
+
+	// A few pixels a tile, scaled with the window but never wider than a
+	// third of it or taller than half.
+	cell := 4 * ui.winHeight / defaultWinHeight
+	if cell < 2 {
+		cell = 2
+	}
+	if cell > ui.winWidth/3/m.width {
+		cell = ui.winWidth / 3 / m.width
+	}
+	if cell > ui.winHeight/2/m.height {
+		cell = ui.winHeight / 2 / m.height
+	}
+	if cell < 1 {
+		cell = 1
+	}
+	w, h := int32(m.width*cell), int32(m.height*cell)
+	x, y := int32(ui.winWidth)-w-10, int32(ui.winHeight)-h-10
+
+	ui.renderer.Copy(ui.eventBackground, nil, &sdl.Rect{x - 4, y - 4, w + 8, h + 8})
+	ui.renderer.Copy(m.texture, nil, &sdl.Rect{x, y, w, h})
+
+	size := int32(cell)
+	mark := func(pos game.Pos, r, g, b uint8) {
+		ui.whitePixel.SetColorMod(r, g, b)
+		ui.renderer.Copy(ui.whitePixel, nil, &sdl.Rect{x + int32(pos.X)*size, y + int32(pos.Y)*size, size, size})
+	}
+	for pos := range level.Monsters {
+		if level.Map[pos.Y][pos.X].Visible {
+			mark(pos, 220, 40, 40)
+		}
+	}
+	mark(level.Player.Pos, 60, 230, 60)
+}
@@ -0,0 +1,90 @@
+package ui
+
+import (
+	"github.com/michaelilao/gorpg/game"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// keyRepeat is a movement key being held down. It remembers what the player
+// could see when it was pressed so that walking stops as soon as anything
+// new turns up. Each level received is a fresh copy, so monsters are
+// counted rather than told apart.
+type keyRepeat struct {
+	binding  binding
+	next     uint32
+	eventPos int
+	lastSeen string
+	monsters int
+}
+
+func isMovement(typ game.InputType) bool {
+	switch typ {
+	case game.Up, game.Down, game.Left, game.Right,
+		game.UpLeft, game.UpRight, game.DownLeft, game.DownRight:
+		return true
+	}
+	return false
+}
+
+func (ui *ui) startRepeat(b binding) {
+	if ui.level == nil {
+		return
+	}
+	ui.repeat = &keyRepeat{}
+	ui.repeat.binding = b
+	ui.repeat.next = sdl.GetTicks() + ui.keys.repeatDelay
+	ui.repeat.eventPos, ui.repeat.lastSeen = latestEvent(ui.level)
+	ui.repeat.monsters = visibleMonsters(ui.level)
+}
+
+// repeatedInput returns the held movement input once it is due again, or
+// game.None. Repeats wait until the game has answered the last input so
+// they don't pile up.
+func (ui *ui) repeatedInput() game.InputType {
+	r := ui.repeat
+	if r == nil {
+		return game.None
+	}
+	if ui.keyboardState[r.binding.scancode] == 0 || currentMods() != r.binding.mods {
+		ui.repeat = nil
+		return game.None
+	}
+	now := sdl.GetTicks()
+	if ui.awaitingLevel || now < r.next {
+		return game.None
+	}
+	r.next = now + ui.keys.repeatInterval
+	return r.binding.input
+}
+
+// checkRepeat stops a held key from repeating once a new level shows more
+// monsters in view than before or a new event.
+func (ui *ui) checkRepeat(level *game.Level) {
+	r := ui.repeat
+	if r == nil {
+		return
+	}
+	eventPos, lastSeen := latestEvent(level)
+	if eventPos != r.eventPos || lastSeen != r.lastSeen {
+		ui.repeat = nil
+		return
+	}
+	if visibleMonsters(level) > r.monsters {
+		ui.repeat = nil
+	}
+}
+
+func latestEvent(level *game.Level) (int, string) {
+	last := (level.EventPos + len(level.Events) - 1) % len(level.Events)
+	return level.EventPos, level.Events[last]
+}
+
+func visibleMonsters(level *game.Level) int {
+	count := 0
+	for pos := range level.Monsters {
+		if level.Map[pos.Y][pos.X].Visible {
+			count++
+		}
+	}
+	return count
+}
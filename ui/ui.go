@@ -3,6 +3,7 @@ package ui
 import (
 	"bufio"
 	"image/png"
+	"math"
 	"math/rand"
 	"os"
 	"strconv"
@@ -19,7 +20,12 @@ func checkError(err error) {
 	}
 }
 
-const winWidth, winHeight = 1280, 720
+// The window opens at this size; it can be resized or made fullscreen
+// afterwards. Fonts are scaled by the window height relative to
+// defaultWinHeight.
+const defaultWinWidth, defaultWinHeight = 1280, 720
+
+const fontFile = "ui/assets/font.ttf"
 
 type ui struct {
 	winWidth          int
@@ -30,15 +36,28 @@ type ui struct {
 	textureIndex      map[rune][]sdl.Rect
 	prevKeyBoardState []uint8
 	keyboardState     []uint8
-	centerX           int
-	centerY           int
+	camera            camera
+	zoom              int
 	r                 *rand.Rand
 	levelChan         chan *game.Level
 	inputChan         chan *game.Input
+	level             *game.Level
+	mouseX            int32
+	mouseY            int32
+	mouseOver         bool
+	keys              *keyConfig
+	showBindings      bool
+	repeat            *keyRepeat
+	awaitingLevel     bool
 	fontSmall         *ttf.Font
 	fontMedium        *ttf.Font
 	fontLarge         *ttf.Font
 	eventBackground   *sdl.Texture
+	logBackground     *sdl.Texture
+	whitePixel        *sdl.Texture
+	minimap           minimap
+	showMinimap       bool
+	history           historyView
 	str2TexSm         map[string]*sdl.Texture
 	str2TexMd         map[string]*sdl.Texture
 	str2TexLg         map[string]*sdl.Texture
@@ -128,12 +147,12 @@ func NewUI(inputChan chan *game.Input, levelChan chan *game.Level) *ui {
 
 	ui.levelChan = levelChan
 	ui.r = rand.New(rand.NewSource(1))
-	ui.winWidth = winWidth
-	ui.winHeight = winHeight
+	ui.winWidth = defaultWinWidth
+	ui.winHeight = defaultWinHeight
 
 	var err error
 	ui.window, err = sdl.CreateWindow("RPG", sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED,
-		int32(ui.winWidth), int32(ui.winHeight), sdl.WINDOW_SHOWN)
+		int32(ui.winWidth), int32(ui.winHeight), sdl.WINDOW_SHOWN|sdl.WINDOW_RESIZABLE)
 	checkError(err)
 
 	ui.renderer, err = sdl.CreateRenderer(ui.window, -1, sdl.RENDERER_ACCELERATED)
@@ -144,64 +163,151 @@ func NewUI(inputChan chan *game.Input, levelChan chan *game.Level) *ui {
 	ui.textureAtlas = ui.imgFileToTexture("ui/assets/tiles.png")
 	ui.loadTextureIndex()
 
+	ui.keys, err = loadKeys(configFile("keys.txt", defaultKeysFile))
+	checkError(err)
+	display, err := loadDisplay(configFile("display.txt", defaultDisplayFile))
+	checkError(err)
+	ui.camera.deadZone = display.cameraDeadZone
+	ui.camera.speed = display.cameraSpeed
+	ui.zoom = defaultZoom
+	ui.camera.setTileSize(tileSizes[ui.zoom])
+
 	ui.keyboardState = sdl.GetKeyboardState()
 	ui.prevKeyBoardState = make([]uint8, len(ui.keyboardState))
 	for i, v := range ui.keyboardState {
 		ui.prevKeyBoardState[i] = v
 	}
-	ui.centerX = -1
-	ui.centerY = -1
 	checkError(err)
 
-	ui.fontSmall, err = ttf.OpenFont("ui/assets/font.ttf", int(float64(ui.winWidth)*0.015))
+	ui.loadFonts()
+
+	ui.eventBackground = ui.getSinglePixelTex(sdl.Color{0, 0, 0, 128})
+	ui.eventBackground.SetBlendMode(sdl.BLENDMODE_BLEND)
+	ui.logBackground = ui.getSinglePixelTex(sdl.Color{0, 0, 0, 192})
+	ui.logBackground.SetBlendMode(sdl.BLENDMODE_BLEND)
+	ui.whitePixel = ui.getSinglePixelTex(sdl.Color{255, 255, 255, 255})
+	return ui
+}
+
+// loadFonts opens the fonts at sizes to suit the window height, dropping
+// any text already rendered with the old ones.
+func (ui *ui) loadFonts() {
+	scale := float64(ui.winHeight) / defaultWinHeight
+	size := func(base float64) int {
+		return int(math.Max(8, math.Round(base*scale)))
+	}
+	for _, font := range []*ttf.Font{ui.fontSmall, ui.fontMedium, ui.fontLarge} {
+		if font != nil {
+			font.Close()
+		}
+	}
+	var err error
+	ui.fontSmall, err = ttf.OpenFont(fontFile, size(19))
 	checkError(err)
 
-	ui.fontMedium, err = ttf.OpenFont("ui/assets/font.ttf", 32)
+	ui.fontMedium, err = ttf.OpenFont(fontFile, size(32))
 	checkError(err)
 
-	ui.fontLarge, err = ttf.OpenFont("ui/assets/font.ttf", 64)
+	ui.fontLarge, err = ttf.OpenFont(fontFile, size(64))
 	checkError(err)
 
-	ui.eventBackground = ui.getSinglePixelTex(sdl.Color{0, 0, 0, 128})
-	ui.eventBackground.SetBlendMode(sdl.BLENDMODE_BLEND)
-	return ui
+	for _, cache := range []map[string]*sdl.Texture{ui.str2TexSm, ui.str2TexMd, ui.str2TexLg} {
+		for s, tex := range cache {
+			tex.Destroy()
+			delete(cache, s)
+		}
+	}
+}
+
+// resize lays the screen out again for a new window size.
+func (ui *ui) resize(width, height int32) {
+	ui.winWidth, ui.winHeight = int(width), int(height)
+	ui.loadFonts()
+	ui.recentre()
+}
+
+// setZoom moves delta steps through tileSizes, stopping at either end.
+func (ui *ui) setZoom(delta int) {
+	zoom := ui.zoom + delta
+	if zoom < 0 || zoom >= len(tileSizes) {
+		return
+	}
+	ui.zoom = zoom
+	ui.camera.setTileSize(tileSizes[zoom])
+	ui.recentre()
+}
+
+// recentre puts the camera straight where it belongs after the window or
+// the tiles change size.
+func (ui *ui) recentre() {
+	if ui.level != nil {
+		ui.camera.follow(ui.level, ui.winWidth, ui.winHeight)
+		ui.camera.jump()
+	}
+}
+
+func (ui *ui) toggleFullscreen() {
+	var flags uint32
+	if ui.window.GetFlags()&sdl.WINDOW_FULLSCREEN_DESKTOP != sdl.WINDOW_FULLSCREEN_DESKTOP {
+		flags = sdl.WINDOW_FULLSCREEN_DESKTOP
+	}
+	checkError(ui.window.SetFullscreen(flags))
+}
+
+// offsets is where the top left corner of the map is drawn on screen.
+func (ui *ui) offsets() (int32, int32) {
+	return ui.camera.offsets(ui.winWidth, ui.winHeight)
 }
+
+// screenToPos turns a point in the window into the map position drawn
+// there.
+func (ui *ui) screenToPos(x, y int32) game.Pos {
+	offSetX, offSetY := ui.offsets()
+	size := int32(ui.camera.tileSize)
+	return game.Pos{X: floorDiv(x-offSetX, size), Y: floorDiv(y-offSetY, size)}
+}
+
+func floorDiv(a, b int32) int {
+	q := a / b
+	if a%b != 0 && a < 0 {
+		q--
+	}
+	return int(q)
+}
+
 func (ui *ui) Draw(level *game.Level) {
-	if ui.centerX == -1 && ui.centerY == -1 {
-		ui.centerX = level.Player.X
-		ui.centerY = level.Player.Y
-	}
-	limit := 7
-	//Todo make centering smarter so portals work
-	if level.Player.X > ui.centerX+limit {
-		ui.centerX++
-	} else if level.Player.X < ui.centerX-limit {
-		ui.centerX--
-	} else if level.Player.Y > ui.centerY+limit {
-		ui.centerY++
-	} else if level.Player.Y < ui.centerY-limit {
-		ui.centerY--
-	}
-	offSetX := int32((ui.winWidth / 2) - ui.centerX*32)
-	offSetY := int32((ui.winHeight / 2) - ui.centerY*32)
+	offSetX, offSetY := ui.offsets()
+	size := int32(ui.camera.tileSize)
 
 	ui.r.Seed(1)
 	for y, row := range level.Map {
 		for x, tile := range row {
 			if tile.Rune != game.Blank {
 				srcRects := ui.textureIndex[tile.Rune]
-				srcRect := srcRects[ui.r.Intn(len(srcRects))]
+				var srcRect sdl.Rect
+				if len(srcRects) > 0 {
+					srcRect = srcRects[ui.r.Intn(len(srcRects))]
+				}
 				if tile.Visible || tile.Seen {
-					destRect := sdl.Rect{int32(x*32) + offSetX, int32(y*32) + offSetY, 32, 32}
-					pos := game.Pos{x, y}
-					if level.Debug[pos] {
-						ui.textureAtlas.SetColorMod(128, 0, 0)
-					} else if tile.Seen && !tile.Visible {
-						ui.textureAtlas.SetColorMod(128, 128, 128)
+					destRect := sdl.Rect{int32(x)*size + offSetX, int32(y)*size + offSetY, size, size}
+					pos := game.Pos{X: x, Y: y}
+					if len(srcRects) == 0 {
+						// Terrain with no tile art yet, such as water.
+						var shade uint8 = 255
+						if !tile.Visible {
+							shade = 128
+						}
+						ui.drawRune(tile.Rune, &destRect, shade)
 					} else {
-						ui.textureAtlas.SetColorMod(255, 255, 255)
+						if level.Debug[pos] {
+							ui.textureAtlas.SetColorMod(128, 0, 0)
+						} else if tile.Seen && !tile.Visible {
+							ui.textureAtlas.SetColorMod(128, 128, 128)
+						} else {
+							ui.textureAtlas.SetColorMod(255, 255, 255)
+						}
+						ui.renderer.Copy(ui.textureAtlas, &srcRect, &destRect)
 					}
-					ui.renderer.Copy(ui.textureAtlas, &srcRect, &destRect)
 
 					if tile.OverlayRune != game.Blank {
 						srcRect := ui.textureIndex[tile.OverlayRune][0]
@@ -211,44 +317,199 @@ func (ui *ui) Draw(level *game.Level) {
 			}
 		}
 	}
+	for pos, items := range level.Items {
+		tile := level.Map[pos.Y][pos.X]
+		if tile.Visible || tile.Seen {
+			var shade uint8 = 255
+			if !tile.Visible {
+				shade = 128
+			}
+			item := items[len(items)-1]
+			ui.drawRune(item.Rune, &sdl.Rect{int32(pos.X)*size + offSetX, int32(pos.Y)*size + offSetY, size, size}, shade)
+		}
+	}
 	//21,59
 	ui.textureAtlas.SetColorMod(255, 255, 255)
 	for pos, monster := range level.Monsters {
 		if level.Map[pos.Y][pos.X].Visible {
-			monsterSrcRect := ui.textureIndex[(monster.Rune)][0]
-			ui.renderer.Copy(ui.textureAtlas, &monsterSrcRect, &sdl.Rect{int32(pos.X*32) + offSetX, int32(pos.Y*32) + offSetY, 32, 32})
+			ui.drawRune(monster.Rune, &sdl.Rect{int32(pos.X)*size + offSetX, int32(pos.Y)*size + offSetY, size, size}, 255)
 		}
 	}
 	playerSrcRect := ui.textureIndex['@'][0]
-	ui.renderer.Copy(ui.textureAtlas, &playerSrcRect, &sdl.Rect{int32(level.Player.X*32) + offSetX, int32(level.Player.Y*32) + offSetY, 32, 32})
+	ui.renderer.Copy(ui.textureAtlas, &playerSrcRect, &sdl.Rect{int32(level.Player.X)*size + offSetX, int32(level.Player.Y)*size + offSetY, size, size})
 
 	textStart := int(float64(ui.winHeight) * .68)
 	textWidth := int(float64(ui.winWidth) * .25)
 	ui.renderer.Copy(ui.eventBackground, nil, &sdl.Rect{0, int32(textStart), int32(textWidth), int32(ui.winHeight - textStart)})
 
-	i := level.EventPos
-	count := 0
-
 	_, fontSizeY, _ := ui.fontSmall.SizeUTF8("A")
-	for {
-		event := level.Events[i]
-		if event != "" {
-			tex := ui.stringToTexture(event, sdl.Color{255, 0, 0, 0}, FontSmall)
-			_, _, w, h, err := tex.Query()
-			checkError(err)
-			ui.renderer.Copy(tex, nil, &sdl.Rect{5, int32(count*fontSizeY) + int32(textStart), w, h})
-		}
-		i = (i + 1) % (len(level.Events))
-		count++
-		if i == level.EventPos {
-			break
-		}
+	messages := level.History()
+	rows := (ui.winHeight - textStart) / fontSizeY
+	if len(messages) > rows {
+		messages = messages[len(messages)-rows:]
+	}
+	for i, msg := range messages {
+		tex := ui.stringToTexture(msg.Text, categoryColors[msg.Category], FontSmall)
+		_, _, w, h, err := tex.Query()
+		checkError(err)
+		ui.renderer.Copy(tex, nil, &sdl.Rect{5, int32(i*fontSizeY) + int32(textStart), w, h})
+	}
+	ui.drawHUD(level)
+	if ui.showMinimap {
+		ui.drawMinimap(level)
+	}
+	ui.drawInventory(level.Player)
+	if level.Player.Dead() {
+		ui.drawGameOver(level.Player)
+	} else {
+		ui.drawTooltip(level)
+	}
+	if ui.history.open {
+		ui.drawHistory(level)
+	}
+	if ui.showBindings {
+		ui.drawBindings()
 	}
 	ui.renderer.Present()
 	ui.renderer.Clear()
 
 }
 
+// drawRune draws the atlas tile for r, falling back to the font glyph for
+// runes that have no entry in atlas-index.txt.
+func (ui *ui) drawRune(r rune, destRect *sdl.Rect, shade uint8) {
+	srcRects, exists := ui.textureIndex[r]
+	if exists {
+		ui.textureAtlas.SetColorMod(shade, shade, shade)
+		ui.renderer.Copy(ui.textureAtlas, &srcRects[0], destRect)
+		ui.textureAtlas.SetColorMod(255, 255, 255)
+		return
+	}
+	tex := ui.stringToTexture(string(r), sdl.Color{255, 255, 255, 0}, FontMedium)
+	_, _, w, h, err := tex.Query()
+	checkError(err)
+	if h > destRect.H {
+		w = w * destRect.H / h
+		h = destRect.H
+	}
+	tex.SetColorMod(shade, shade, shade)
+	ui.renderer.Copy(tex, nil, &sdl.Rect{destRect.X + (destRect.W-w)/2, destRect.Y + (destRect.H-h)/2, w, h})
+	tex.SetColorMod(255, 255, 255)
+}
+
+// drawTooltip names whatever is under the mouse pointer.
+func (ui *ui) drawTooltip(level *game.Level) {
+	if !ui.mouseOver {
+		return
+	}
+	text := level.Describe(ui.screenToPos(ui.mouseX, ui.mouseY))
+	if text == "" {
+		return
+	}
+	tex := ui.stringToTexture(text, sdl.Color{255, 255, 255, 0}, FontSmall)
+	_, _, w, h, err := tex.Query()
+	checkError(err)
+	x := ui.mouseX + 16
+	y := ui.mouseY + 16
+	if x+w+4 > int32(ui.winWidth) {
+		x = ui.mouseX - w - 8
+	}
+	if y+h > int32(ui.winHeight) {
+		y = ui.mouseY - h
+	}
+	ui.renderer.Copy(ui.eventBackground, nil, &sdl.Rect{x, y, w + 8, h})
+	ui.renderer.Copy(tex, nil, &sdl.Rect{x + 4, y, w, h})
+}
+
+// click turns a mouse click on the map into an input: the left button
+// attacks a monster next to the player or travels anywhere else, and the
+// right button inspects.
+func (ui *ui) click(e *sdl.MouseButtonEvent) {
+	if ui.level == nil || ui.level.Player.Dead() {
+		return
+	}
+	pos := ui.screenToPos(e.X, e.Y)
+	input := &game.Input{Target: pos}
+	switch e.Button {
+	case sdl.BUTTON_LEFT:
+		input.Typ = game.TravelTo
+		if ui.monsterVisibleAt(pos) && isNextTo(ui.level.Player.Pos, pos) {
+			input.Typ = game.Attack
+		}
+	case sdl.BUTTON_RIGHT:
+		input.Typ = game.Inspect
+	default:
+		return
+	}
+	ui.inputChan <- input
+}
+
+func (ui *ui) monsterVisibleAt(pos game.Pos) bool {
+	if _, exists := ui.level.Monsters[pos]; !exists {
+		return false
+	}
+	return ui.level.Map[pos.Y][pos.X].Visible
+}
+
+func isNextTo(a, b game.Pos) bool {
+	dx, dy := a.X-b.X, a.Y-b.Y
+	return a != b && dx >= -1 && dx <= 1 && dy >= -1 && dy <= 1
+}
+
+func (ui *ui) drawGameOver(player *game.Player) {
+	ui.renderer.Copy(ui.eventBackground, nil, nil)
+
+	killedBy := "Killed by " + player.KilledBy
+	if player.KilledBy == "" {
+		killedBy = "You have perished"
+	}
+	lines := []struct {
+		text string
+		size FontSize
+	}{
+		{"You have died", FontLarge},
+		{killedBy, FontMedium},
+		{"Survived " + strconv.Itoa(player.Turns) + " turns and slew " + strconv.Itoa(player.Kills) + " monsters", FontMedium},
+		{"Press Enter to play again", FontSmall},
+	}
+	y := int32(ui.winHeight / 3)
+	for _, line := range lines {
+		tex := ui.stringToTexture(line.text, sdl.Color{255, 255, 255, 0}, line.size)
+		_, _, w, h, err := tex.Query()
+		checkError(err)
+		ui.renderer.Copy(tex, nil, &sdl.Rect{(int32(ui.winWidth) - w) / 2, y, w, h})
+		y += h + 10
+	}
+}
+
+func (ui *ui) drawInventory(player *game.Player) {
+	if len(player.Inventory) == 0 {
+		return
+	}
+	_, fontSizeY, _ := ui.fontSmall.SizeUTF8("A")
+	panelWidth := int32(float64(ui.winWidth) * .2)
+	panelX := int32(ui.winWidth) - panelWidth
+	ui.renderer.Copy(ui.eventBackground, nil, &sdl.Rect{panelX, 0, panelWidth, int32((len(player.Inventory) + 1) * fontSizeY)})
+
+	tex := ui.stringToTexture("Inventory", sdl.Color{255, 255, 255, 0}, FontSmall)
+	_, _, w, h, err := tex.Query()
+	checkError(err)
+	ui.renderer.Copy(tex, nil, &sdl.Rect{panelX + 5, 0, w, h})
+	for i, item := range player.Inventory {
+		line := "  " + item.Name
+		if i == player.Selected {
+			line = "> " + item.Name
+		}
+		if item.Equipped {
+			line += " (equipped)"
+		}
+		tex := ui.stringToTexture(line, sdl.Color{255, 255, 255, 0}, FontSmall)
+		_, _, w, h, err := tex.Query()
+		checkError(err)
+		ui.renderer.Copy(tex, nil, &sdl.Rect{panelX + 5, int32((i + 1) * fontSizeY), w, h})
+	}
+}
+
 type FontSize int
 
 const (
@@ -280,11 +541,7 @@ func (ui *ui) stringToTexture(s string, color sdl.Color, size FontSize) *sdl.Tex
 		}
 	}
 
-	fontSurface, err := font.RenderUTF8Blended(s, color)
-	checkError(err)
-
-	tex, err := ui.renderer.CreateTextureFromSurface(fontSurface)
-	checkError(err)
+	tex := ui.renderString(s, color, font)
 	switch size {
 	case FontSmall:
 		ui.str2TexSm[s] = tex
@@ -296,11 +553,41 @@ func (ui *ui) stringToTexture(s string, color sdl.Color, size FontSize) *sdl.Tex
 
 	return tex
 }
-func (ui *ui) keyDownOnce(key uint8) bool {
+
+func (ui *ui) renderString(s string, color sdl.Color, font *ttf.Font) *sdl.Texture {
+	fontSurface, err := font.RenderUTF8Blended(s, color)
+	checkError(err)
+	defer fontSurface.Free()
+
+	tex, err := ui.renderer.CreateTextureFromSurface(fontSurface)
+	checkError(err)
+	return tex
+}
+
+// drawString draws s with its top left corner at x, y and returns its
+// width and height. Unlike stringToTexture it doesn't keep the texture, so
+// it suits text such as counters that changes every turn.
+func (ui *ui) drawString(s string, color sdl.Color, size FontSize, x, y int32) (int32, int32) {
+	font := ui.fontSmall
+	switch size {
+	case FontMedium:
+		font = ui.fontMedium
+	case FontLarge:
+		font = ui.fontLarge
+	}
+	tex := ui.renderString(s, color, font)
+	defer tex.Destroy()
+	_, _, w, h, err := tex.Query()
+	checkError(err)
+	ui.renderer.Copy(tex, nil, &sdl.Rect{x, y, w, h})
+	return w, h
+}
+
+func (ui *ui) keyDownOnce(key sdl.Scancode) bool {
 	return ui.keyboardState[key] == 1 && ui.prevKeyBoardState[key] == 0
 }
 
-func (ui *ui) keyPressed(key uint8) bool {
+func (ui *ui) keyPressed(key sdl.Scancode) bool {
 	return ui.keyboardState[key] == 0 && ui.prevKeyBoardState[key] == 1
 }
 
@@ -318,6 +605,7 @@ func (ui *ui) getSinglePixelTex(color sdl.Color) *sdl.Texture {
 }
 
 func (ui *ui) Run() {
+	redraw := false
 	for {
 		for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
 			switch e := event.(type) {
@@ -327,6 +615,33 @@ func (ui *ui) Run() {
 				if e.Event == sdl.WINDOWEVENT_CLOSE {
 					ui.inputChan <- &game.Input{Typ: game.CloseWindow, LevelChannel: ui.levelChan}
 				}
+				if e.Event == sdl.WINDOWEVENT_LEAVE {
+					ui.mouseOver = false
+					redraw = true
+				}
+				if e.Event == sdl.WINDOWEVENT_SIZE_CHANGED {
+					ui.resize(e.Data1, e.Data2)
+					redraw = true
+				}
+			case *sdl.MouseMotionEvent:
+				ui.mouseX, ui.mouseY = e.X, e.Y
+				ui.mouseOver = true
+				redraw = true
+			case *sdl.MouseWheelEvent:
+				if ui.history.open {
+					ui.history.scroll += int(e.Y)
+				} else if e.Y > 0 {
+					ui.setZoom(1)
+				} else if e.Y < 0 {
+					ui.setZoom(-1)
+				}
+				redraw = true
+			case *sdl.MouseButtonEvent:
+				// Clicks would land on the map hidden behind the log or
+				// the bindings list.
+				if e.Type == sdl.MOUSEBUTTONDOWN && !ui.history.open && !ui.showBindings {
+					ui.click(e)
+				}
 			}
 
 		}
@@ -334,30 +649,67 @@ func (ui *ui) Run() {
 		select {
 		case newLevel, ok := <-ui.levelChan:
 			if ok {
-				ui.Draw(newLevel)
+				ui.level = newLevel
+				ui.awaitingLevel = false
+				ui.camera.follow(newLevel, ui.winWidth, ui.winHeight)
+				ui.checkRepeat(newLevel)
+				redraw = true
 			}
 		default:
 		}
+		if ui.camera.update(sdl.GetTicks()) {
+			redraw = true
+		}
+		if redraw && ui.level != nil {
+			ui.Draw(ui.level)
+			redraw = false
+		}
 
 		if sdl.GetKeyboardFocus() == ui.window && sdl.GetMouseFocus() == ui.window {
 
 			var input game.Input
-			if ui.keyDownOnce(sdl.SCANCODE_UP) {
-				input.Typ = game.Up
-			}
-			if ui.keyDownOnce(sdl.SCANCODE_DOWN) {
-				input.Typ = game.Down
+			b, pressed := ui.boundInput()
+			if !pressed {
+				b = binding{input: ui.repeatedInput()}
+			} else if b.action == sendInput && isMovement(b.input) && !ui.showBindings && !ui.history.open {
+				ui.startRepeat(b)
 			}
-			if ui.keyDownOnce(sdl.SCANCODE_LEFT) {
-				input.Typ = game.Left
-			}
-			if ui.keyDownOnce(sdl.SCANCODE_RIGHT) {
-				input.Typ = game.Right
+			switch {
+			case b.action == showBindings:
+				ui.showBindings = !ui.showBindings
+				redraw = true
+			case ui.showBindings:
+				if ui.keyDownOnce(sdl.SCANCODE_ESCAPE) {
+					ui.showBindings = false
+					redraw = true
+				}
+			case b.action == showHistory:
+				ui.history.open = !ui.history.open
+				ui.history.scroll = 0
+				redraw = true
+			case ui.history.open:
+				if ui.historyKeys() {
+					redraw = true
+				}
+			case b.action == toggleMinimap:
+				ui.showMinimap = !ui.showMinimap
+				redraw = true
+			case b.action == toggleFullscreen:
+				ui.toggleFullscreen()
+			case b.action == zoomIn:
+				ui.setZoom(1)
+				redraw = true
+			case b.action == zoomOut:
+				ui.setZoom(-1)
+				redraw = true
+			case b.action == sendInput:
+				input.Typ = b.input
 			}
 			for i, v := range ui.keyboardState {
 				ui.prevKeyBoardState[i] = v
 			}
 			if input.Typ != game.None {
+				ui.awaitingLevel = true
 				ui.inputChan <- &input
 			}
 			sdl.Delay(10)